@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
@@ -50,7 +51,8 @@ func saveImageFile(r *http.Request, formKey string) (string, error) {
 
 // handleAdminPage renders the products
 func handleAdminPage(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query("SELECT id, category, name, description, price, image_url, type_tag, in_stock FROM products")
+	// The In Stock box is the hand-set 86; caps and stock sell products out on their own
+	rows, err := db.Query("SELECT id, category, name, description, price, image_url, type_tag, COALESCE(manual_sold_out, 0) = 0, COALESCE(daily_cap, 0), COALESCE(allergens, ''), COALESCE(vegetarian, 0), COALESCE(spicy_level, 0), COALESCE(search_tags, '') FROM products")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -61,7 +63,10 @@ func handleAdminPage(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var p Product
 		var imgUrl sql.NullString
-		rows.Scan(&p.ID, &p.Category, &p.Name, &p.Description, &p.Price, &imgUrl, &p.TypeTag, &p.InStock)
+		var allergens string
+		rows.Scan(&p.ID, &p.Category, &p.Name, &p.Description, &p.Price, &imgUrl, &p.TypeTag, &p.InStock, &p.DailyCap,
+			&allergens, &p.Dietary.Vegetarian, &p.Dietary.Spicy, &p.SearchTags)
+		p.Dietary.Allergens = parseAllergens(allergens)
 		if imgUrl.Valid {
 			p.ImageURL = imgUrl.String
 		}
@@ -129,19 +134,34 @@ func handleAdminPage(w http.ResponseWriter, r *http.Request) {
             <a href="/" class="no-underline text-gray-800 flex items-center gap-2 hover:text-blue-600 transition">
                 <span class="text-xl font-bold">⬅ Back to Menu</span>
             </a>
+            <a href="/admin/orders" class="font-semibold hover:text-blue-600">🧾 Orders</a>
+            <a href="/admin/reports" class="font-semibold hover:text-blue-600">📊 Reports</a>
+            <a href="/admin/inventory" class="font-semibold hover:text-blue-600">📦 Inventory</a>
+            <a href="/admin/purchasing" class="font-semibold hover:text-blue-600">🚚 Purchasing</a>
+            <a href="/admin/schedules" class="font-semibold hover:text-blue-600">⏰ Schedules</a>
+            <a href="/admin/nutrition" class="font-semibold hover:text-blue-600">🥗 Nutrition</a>
+            <a href="/admin/close" class="font-semibold hover:text-blue-600">🔒 Close Day</a>
             <h2 class="text-xl font-semibold text-gray-500">Live Admin Editor</h2>
         </div>
+        <!-- Manager alerts (late tickets) -->
+        <div hx-get="/admin/alerts" hx-trigger="load, every 30s"></div>
     </header>
 
     <main class="max-w-7xl mx-auto px-4 space-y-12">`)
 
+	// Translations for the product cards' language fields
+	translations := map[string]translator{}
+	for _, lang := range translatedLanguages() {
+		translations[lang] = loadTranslator(lang)
+	}
+
 	// 1. Render Existing Categories and Products
 	for _, cat := range sortedCategories {
 		products := categories[cat]
 		fmt.Fprintf(w, "<section><h2 class='text-2xl font-bold mb-6 text-gray-800 border-b pb-2'>%s</h2><div class='grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 xl:grid-cols-4 gap-6'>", strings.ToUpper(cat))
 
 		for _, p := range products {
-			renderAdminCard(w, p)
+			renderAdminCard(w, p, translations)
 		}
 		renderAddCard(w, cat)
 
@@ -151,6 +171,12 @@ func handleAdminPage(w http.ResponseWriter, r *http.Request) {
 	// 2. Render "Create New Category" Section
 	renderNewCategorySection(w)
 
+	// 3. Allergens on add-ons
+	renderModifierDietarySection(w)
+
+	// 4. Kitchen language and category/add-on translations
+	renderLanguagesSection(w)
+
 	fmt.Fprint(w, `</main>
 	<script>
 		// 🆕 HTMX Handler for Save State
@@ -284,7 +310,7 @@ func renderAddCard(w http.ResponseWriter, category string) {
 		</form>`, strings.Title(category))
 }
 
-func renderAdminCard(w http.ResponseWriter, p Product) {
+func renderAdminCard(w http.ResponseWriter, p Product, translations map[string]translator) {
 	opacityClass := ""
 	if !p.InStock {
 		opacityClass = "opacity-60 grayscale-[0.8]"
@@ -319,15 +345,26 @@ func renderAdminCard(w http.ResponseWriter, p Product) {
 						<span class="font-bold text-gray-800">RM <input type="number" step="0.01" name="price" value="%.2f" class="w-20 p-1 border border-dashed border-gray-300 rounded bg-transparent focus:bg-white focus:border-blue-500 focus:outline-none"></span>
 						<label class="flex items-center gap-2 text-sm cursor-pointer select-none"><input type="checkbox" name="in_stock" %s class="rounded text-blue-600"> In Stock</label>
 					</div>
+					%s
+					<label class="flex items-center gap-2 text-xs text-gray-600">Daily limit <input type="number" min="0" step="1" name="daily_cap" value="%d" class="w-16 p-1 border border-dashed border-gray-300 rounded bg-transparent focus:bg-white focus:border-blue-500 focus:outline-none"> <span class="text-gray-400">0 = unlimited</span></label>
+					%s
+					<input type="text" name="search_tags" value="%s" placeholder="Search tags (e.g. soda, soft drink)" class="w-full text-xs p-1 border border-dashed border-gray-300 rounded bg-transparent focus:bg-white focus:border-blue-500 focus:outline-none">
+					%s
 					<button type="submit" class="btn-save w-full py-2 rounded font-medium shadow transition-all duration-300 opacity-0 pointer-events-none">💾 Save Changes</button>
 				</div>
 			</div>
 		</form>`,
-		p.Name, p.Description, p.Price, checked)
+		p.Name, p.Description, p.Price, checked, costLineHTML(p), p.DailyCap, dietaryInputs(p.Dietary, true), html.EscapeString(p.SearchTags),
+		productTranslationInputs(p.ID, translations))
 }
 
 // ---------------- HANDLERS ----------------
 
+// handleAdminAlerts renders the late-order banner for managers
+func handleAdminAlerts(w http.ResponseWriter, r *http.Request) {
+	renderSLABanner(w, "background:#dc2626; color:#fff; font-weight:bold; padding:8px 16px; text-align:center;")
+}
+
 func handleAdminGenerateImage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -422,13 +459,17 @@ func handleAdminCreateProduct(w http.ResponseWriter, r *http.Request) {
 		imagePath = "https://placehold.co/400x300?text=No+Image"
 	}
 
-	_, err = db.Exec(`INSERT INTO products (category, name, description, price, in_stock, image_url, type_tag) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		category, name, desc, price, inStock, imagePath, "")
+	res, err := db.Exec(`INSERT INTO products (category, name, description, price, in_stock, manual_sold_out, image_url, type_tag) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		category, name, desc, price, inStock, !inStock, imagePath, "")
 
 	if err != nil {
 		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	newID, _ := res.LastInsertId()
+	syncMenuSearch(newID)
+	// New categories become a KDS station too
+	db.Exec("INSERT OR IGNORE INTO categories (name) VALUES (?)", category)
 
 	handleAdminPage(w, r)
 }
@@ -445,6 +486,17 @@ func handleAdminUpdateProduct(w http.ResponseWriter, r *http.Request) {
 	desc := r.FormValue("description")
 	price, _ := strconv.ParseFloat(r.FormValue("price"), 64)
 	inStock := (r.FormValue("in_stock") == "on")
+	dailyCap, _ := strconv.Atoi(r.FormValue("daily_cap"))
+	if dailyCap < 0 {
+		dailyCap = 0
+	}
+	allergens := cleanAllergens(r.Form["allergens"])
+	searchTags := strings.TrimSpace(r.FormValue("search_tags"))
+	vegetarian := r.FormValue("vegetarian") == "on"
+	spicy, _ := strconv.Atoi(r.FormValue("spicy_level"))
+	if spicy < 0 || spicy >= len(spicyLabels) {
+		spicy = 0
+	}
 
 	newImagePath, _ := saveImageFile(r, "image")
 	if newImagePath == "" {
@@ -453,17 +505,26 @@ func handleAdminUpdateProduct(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 	if newImagePath != "" {
-		_, err = db.Exec(`UPDATE products SET name=?, description=?, price=?, in_stock=?, image_url=? WHERE id=?`,
-			name, desc, price, inStock, newImagePath, id)
+		_, err = db.Exec(`UPDATE products SET name=?, description=?, price=?, manual_sold_out=?, daily_cap=?, allergens=?, vegetarian=?, spicy_level=?, search_tags=?, image_url=? WHERE id=?`,
+			name, desc, price, !inStock, dailyCap, allergens, vegetarian, spicy, searchTags, newImagePath, id)
 	} else {
-		_, err = db.Exec(`UPDATE products SET name=?, description=?, price=?, in_stock=? WHERE id=?`,
-			name, desc, price, inStock, id)
+		_, err = db.Exec(`UPDATE products SET name=?, description=?, price=?, manual_sold_out=?, daily_cap=?, allergens=?, vegetarian=?, spicy_level=?, search_tags=? WHERE id=?`,
+			name, desc, price, !inStock, dailyCap, allergens, vegetarian, spicy, searchTags, id)
 	}
 
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	if err := saveProductTranslations(id, r); err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	// A raised or removed limit can put a special back on sale (or take it off);
+	// this also applies the In Stock box
+	refreshDailyCaps(db, businessDay(time.Now()))
+	syncMenuSearch(int64(id))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -477,5 +538,9 @@ func handleAdminDeleteProduct(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// Its availability schedules go with it
+	db.Exec("DELETE FROM availability_windows WHERE product_id = ?", idStr)
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	syncMenuSearch(id)
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReceiptSender delivers a copy of the receipt to the customer (SMS, WhatsApp, email, ...)
+type ReceiptSender interface {
+	SendReceipt(o Order, link string) error
+}
+
+// receiptSender is swapped for a real SMS/WhatsApp gateway once one is set up.
+// Until then receipts are only written to the server log.
+var receiptSender ReceiptSender = logReceipts{}
+
+type logReceipts struct{}
+
+func (logReceipts) SendReceipt(o Order, link string) error {
+	log.Printf("Receipt for order #%s to %q: RM%.2f %s", o.Number(), o.Phone, o.Total, link)
+	return nil
+}
+
+// adminPageStart writes the shared header of the admin sub-pages
+func adminPageStart(w http.ResponseWriter, title string) {
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>%s - Apipizza Admin</title>
+    <script src="https://cdn.tailwindcss.com"></script>
+    <script src="https://unpkg.com/htmx.org@1.9.10"></script>
+</head>
+<body class="bg-gray-50 text-gray-800 font-sans pb-20">
+    <header class="bg-white shadow mb-8 sticky top-0 z-50">
+        <div class="max-w-7xl mx-auto px-4 py-4 flex items-center justify-between">
+            <nav class="flex items-center gap-6 font-semibold">
+                <a href="/admin" class="hover:text-blue-600">🍕 Products</a>
+                <a href="/admin/orders" class="hover:text-blue-600">🧾 Orders</a>
+                <a href="/admin/reports" class="hover:text-blue-600">📊 Reports</a>
+                <a href="/admin/inventory" class="hover:text-blue-600">📦 Inventory</a>
+                <a href="/admin/purchasing" class="hover:text-blue-600">🚚 Purchasing</a>
+                <a href="/admin/schedules" class="hover:text-blue-600">⏰ Schedules</a>
+                <a href="/admin/nutrition" class="hover:text-blue-600">🥗 Nutrition</a>
+                <a href="/admin/close" class="hover:text-blue-600">🔒 Close Day</a>
+            </nav>
+            <h2 class="text-xl font-semibold text-gray-500">%s</h2>
+        </div>
+        <div hx-get="/admin/alerts" hx-trigger="load, every 30s"></div>
+    </header>
+    <main class="max-w-7xl mx-auto px-4 space-y-8">`, title, title)
+}
+
+func adminPageEnd(w http.ResponseWriter) {
+	fmt.Fprint(w, `</main></body></html>`)
+}
+
+// adminOrderFilter builds the WHERE clause for the order list and its CSV export
+func adminOrderFilter(q url.Values) (string, []interface{}) {
+	where := "1 = 1"
+	var args []interface{}
+	if from := q.Get("from"); from != "" {
+		where += " AND business_day >= ?"
+		args = append(args, from)
+	}
+	if to := q.Get("to"); to != "" {
+		where += " AND business_day <= ?"
+		args = append(args, to)
+	}
+	if status := q.Get("status"); status != "" {
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+	if channel := q.Get("channel"); channel != "" {
+		where += " AND COALESCE(channel, 'web') = ?"
+		args = append(args, channel)
+	}
+	if payment := q.Get("payment"); payment != "" {
+		where += " AND COALESCE(payment_method, 'card') = ?"
+		args = append(args, payment)
+	}
+	return where, args
+}
+
+// filterSelect renders a <select> with an "All" option, keeping the current choice
+func filterSelect(name, current string, values []string) string {
+	out := fmt.Sprintf(`<select name="%s" class="border rounded px-2 py-1 bg-white"><option value="">All</option>`, name)
+	for _, v := range values {
+		selected := ""
+		if v == current {
+			selected = " selected"
+		}
+		out += fmt.Sprintf(`<option value="%s"%s>%s</option>`, v, selected, v)
+	}
+	return out + `</select>`
+}
+
+// handleAdminOrders lists orders with date/status/channel/payment filters
+func handleAdminOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if len(q) == 0 {
+		today := businessDay(time.Now())
+		q.Set("from", today)
+		q.Set("to", today)
+	}
+	where, args := adminOrderFilter(q)
+	orders := getOrdersByQuery(`SELECT `+orderColumns+` FROM orders WHERE `+where+` ORDER BY id DESC LIMIT 500`, args...)
+
+	var channels []string
+	for ch := range channelPrefixes {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	statuses := []string{StatusPaid, StatusCompleted, StatusPickedUp, StatusCancelled}
+
+	adminPageStart(w, "Orders")
+	fmt.Fprintf(w, `
+    <form method="get" class="bg-white rounded-lg shadow p-4 flex flex-wrap items-end gap-4 text-sm">
+        <label class="flex flex-col">From <input type="date" name="from" value="%s" class="border rounded px-2 py-1"></label>
+        <label class="flex flex-col">To <input type="date" name="to" value="%s" class="border rounded px-2 py-1"></label>
+        <label class="flex flex-col">Status %s</label>
+        <label class="flex flex-col">Channel %s</label>
+        <label class="flex flex-col">Payment %s</label>
+        <button class="bg-gray-900 text-white px-4 py-1.5 rounded font-semibold">Filter</button>
+        <a href="/admin/orders/export?%s" class="ml-auto text-blue-600 hover:underline">⬇ Export CSV</a>
+    </form>`,
+		html.EscapeString(q.Get("from")), html.EscapeString(q.Get("to")),
+		filterSelect("status", q.Get("status"), statuses),
+		filterSelect("channel", q.Get("channel"), channels),
+		filterSelect("payment", q.Get("payment"), paymentMethods),
+		html.EscapeString(q.Encode()))
+
+	total := 0.0
+	fmt.Fprint(w, `
+    <table class="w-full bg-white rounded-lg shadow text-sm">
+        <thead class="bg-gray-100 text-left"><tr>
+            <th class="p-2">Pickup #</th><th class="p-2">Placed</th><th class="p-2">Customer</th><th class="p-2">Items</th>
+            <th class="p-2">Channel</th><th class="p-2">Payment</th><th class="p-2">Status</th><th class="p-2 text-right">Total</th>
+        </tr></thead><tbody>`)
+	for _, o := range orders {
+		total += o.Total
+		fmt.Fprintf(w, `
+            <tr class="border-t hover:bg-orange-50 cursor-pointer" onclick="location.href='/admin/orders/detail?id=%d'">
+                <td class="p-2 font-bold">%s</td><td class="p-2">%s</td><td class="p-2">%s<br><small class="text-gray-500">%s</small></td>
+                <td class="p-2">%d</td><td class="p-2">%s</td><td class="p-2">%s</td><td class="p-2">%s</td><td class="p-2 text-right">RM%.2f</td>
+            </tr>`,
+			o.ID, o.Number(), parseDBTime(o.CreatedAt).Local().Format("Jan 2, 3:04 pm"),
+			html.EscapeString(o.Customer), html.EscapeString(o.Phone), len(o.Items), o.Channel, o.Payment, o.Status, o.Total)
+	}
+	if len(orders) == 0 {
+		fmt.Fprint(w, `<tr><td colspan="8" class="p-6 text-center text-gray-400">No orders match these filters</td></tr>`)
+	}
+	fmt.Fprintf(w, `</tbody>
+        <tfoot><tr class="border-t font-bold"><td class="p-2" colspan="7">%d orders</td><td class="p-2 text-right">RM%.2f</td></tr></tfoot>
+    </table>`, len(orders), total)
+	adminPageEnd(w)
+}
+
+// handleAdminOrdersExport downloads the filtered order list as CSV, one row per line item
+func handleAdminOrdersExport(w http.ResponseWriter, r *http.Request) {
+	where, args := adminOrderFilter(r.URL.Query())
+	orders := getOrdersByQuery(`SELECT `+orderColumns+` FROM orders WHERE `+where+` ORDER BY id ASC`, args...)
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="orders.csv"`)
+	out := csv.NewWriter(w)
+	out.Write([]string{"order_id", "pickup_number", "created_at", "customer", "phone", "channel", "payment_method", "status", "order_total", "refunded", "item", "options", "remarks", "item_price", "voided"})
+	for _, o := range orders {
+		refunded := fmt.Sprintf("%.2f", refundedAmount(o.ID))
+		for _, item := range o.Items {
+			out.Write([]string{
+				strconv.Itoa(o.ID), o.Number(), o.CreatedAt, o.Customer, o.Phone, o.Channel, o.Payment, o.Status,
+				fmt.Sprintf("%.2f", o.Total), refunded,
+				item.Name, strings.Join(item.ModifierNames(), ", "), item.Remark(),
+				fmt.Sprintf("%.2f", item.Price), strconv.FormatBool(item.Voided),
+			})
+		}
+	}
+	out.Flush()
+}
+
+type Refund struct {
+	Amount    float64
+	Reason    string
+	Status    string
+	CreatedAt string
+}
+
+func getRefunds(orderID int) []Refund {
+	rows, err := db.Query("SELECT amount, COALESCE(reason, ''), status, created_at FROM refunds WHERE order_id = ? ORDER BY id", orderID)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var refunds []Refund
+	for rows.Next() {
+		var rf Refund
+		rows.Scan(&rf.Amount, &rf.Reason, &rf.Status, &rf.CreatedAt)
+		refunds = append(refunds, rf)
+	}
+	return refunds
+}
+
+// handleAdminOrderDetail shows one order with items, refunds, timeline and manager actions
+func handleAdminOrderDetail(w http.ResponseWriter, r *http.Request) {
+	o, ok := getOrder(r.FormValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	adminPageStart(w, "Order #"+o.Number())
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		fmt.Fprintf(w, `<div class="bg-green-50 border border-green-200 text-green-800 rounded p-3">%s</div>`, html.EscapeString(msg))
+	}
+
+	fmt.Fprintf(w, `
+    <div class="grid md:grid-cols-3 gap-6">
+        <section class="md:col-span-2 bg-white rounded-lg shadow p-6">
+            <h1 class="text-3xl font-black text-orange-600">#%s <small class="text-sm text-gray-400">ref %d</small></h1>
+            <p class="text-gray-600">%s · %s · %s · %s · <b>%s</b></p>`,
+		o.Number(), o.ID, html.EscapeString(o.Customer), html.EscapeString(o.Phone),
+		parseDBTime(o.CreatedAt).Local().Format("Jan 2 2006, 3:04 pm"), o.Channel+" / "+o.Payment, o.Status)
+	if o.Allergy != "" {
+		fmt.Fprintf(w, `<p class="mt-2 bg-pink-50 border border-pink-300 text-pink-800 font-bold rounded p-2">⚠️ Allergy: %s</p>`, html.EscapeString(o.Allergy))
+	}
+	fmt.Fprint(w, `
+            <ul class="divide-y mt-4">`)
+
+	for _, item := range o.Items {
+		detail := html.EscapeString(strings.Join(item.ModifierNames(), ", "))
+		if remark := item.Remark(); remark != "" {
+			detail += fmt.Sprintf(` <span class="text-orange-600 italic">Note: %s</span>`, html.EscapeString(remark))
+		}
+		cls := ""
+		if item.Voided {
+			cls = "line-through text-gray-400"
+		}
+		fmt.Fprintf(w, `<li class="py-2 flex justify-between %s"><div>%s<div class="text-xs text-gray-500">%s</div></div><span>RM%.2f</span></li>`,
+			cls, html.EscapeString(item.Name), detail, item.Price)
+	}
+
+	refunded := refundedAmount(o.ID)
+	fmt.Fprintf(w, `</ul>
+            <div class="flex justify-between font-bold border-t pt-2"><span>Total (incl. tax)</span><span>RM%.2f</span></div>
+            <div class="flex justify-between text-red-600"><span>Refunded</span><span>-RM%.2f</span></div>`, o.Total, refunded)
+	if due := surchargeDue(o.ID); due > 0.005 && o.Status != StatusCancelled {
+		fmt.Fprintf(w, `<div class="flex justify-between text-orange-600"><span>Still to collect (amended)</span><span>RM%.2f</span></div>`, due)
+	}
+
+	for _, rf := range getRefunds(o.ID) {
+		fmt.Fprintf(w, `<div class="text-xs text-gray-500 flex justify-between"><span>%s · %s</span><span>RM%.2f (%s)</span></div>`,
+			parseDBTime(rf.CreatedAt).Local().Format("Jan 2, 3:04 pm"), html.EscapeString(rf.Reason), rf.Amount, rf.Status)
+	}
+
+	fmt.Fprint(w, `
+            <h2 class="font-bold mt-6 mb-2">Timeline</h2>
+            <table class="w-full text-sm">`)
+	for _, e := range getOrderEvents(o.ID) {
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 text-gray-500">%s</td><td class="py-1 font-semibold">%s</td><td class="py-1">%s</td></tr>`,
+			parseDBTime(e.CreatedAt).Local().Format("Jan 2, 3:04:05 pm"), e.Event, html.EscapeString(e.Detail))
+	}
+	fmt.Fprint(w, `</table>
+        </section>
+        <aside class="space-y-4">`)
+
+	reasons := ""
+	for _, reason := range changeReasons {
+		reasons += fmt.Sprintf(`<option>%s</option>`, reason)
+	}
+	locked := orderLocked(o.ID)
+	if locked {
+		fmt.Fprint(w, `<div class="bg-gray-900 text-white rounded-lg p-4 text-sm">🔒 This business day is closed. The order can no longer be refunded or cancelled.</div>`)
+	}
+	if remaining := o.Total - refunded; remaining > 0.005 && !locked {
+		fmt.Fprintf(w, `
+            <form method="post" action="/admin/orders/refund" class="bg-white rounded-lg shadow p-4 space-y-2 text-sm">
+                <h3 class="font-bold">💸 Refund</h3>
+                <input type="hidden" name="id" value="%d">
+                <input type="number" name="amount" step="0.01" min="0.01" max="%.2f" value="%.2f" class="w-full border rounded px-2 py-1">
+                <select name="reason" class="w-full border rounded px-2 py-1">%s</select>
+                <button class="w-full bg-orange-600 text-white rounded py-1.5 font-semibold" onclick="return confirm('Issue this refund?')">Refund</button>
+            </form>`, o.ID, remaining, remaining, reasons)
+	}
+	if o.Status != StatusCancelled && !locked {
+		fmt.Fprintf(w, `
+            <form method="post" action="/admin/orders/cancel" class="bg-white rounded-lg shadow p-4 space-y-2 text-sm">
+                <h3 class="font-bold">✖ Cancel order</h3>
+                <input type="hidden" name="id" value="%d">
+                <select name="reason" class="w-full border rounded px-2 py-1">%s</select>
+                <button class="w-full bg-red-600 text-white rounded py-1.5 font-semibold" onclick="return confirm('Cancel and refund the whole order?')">Cancel &amp; refund</button>
+            </form>`, o.ID, reasons)
+	}
+	fmt.Fprintf(w, `
+            <form method="post" action="/admin/orders/receipt" class="bg-white rounded-lg shadow p-4 space-y-2 text-sm">
+                <h3 class="font-bold">📨 Resend receipt</h3>
+                <input type="hidden" name="id" value="%d">
+                <input type="tel" name="phone" value="%s" placeholder="Phone" class="w-full border rounded px-2 py-1">
+                <button class="w-full bg-gray-900 text-white rounded py-1.5 font-semibold">Send</button>
+            </form>
+            <a href="/admin/orders" class="block text-center text-blue-600 hover:underline">⬅ All orders</a>
+        </aside>
+    </div>`, o.ID, html.EscapeString(o.Phone))
+	adminPageEnd(w)
+}
+
+func redirectToAdminOrder(w http.ResponseWriter, r *http.Request, orderID int, msg string) {
+	http.Redirect(w, r, fmt.Sprintf("/admin/orders/detail?id=%d&msg=%s", orderID, url.QueryEscape(msg)), http.StatusSeeOther)
+}
+
+// handleAdminOrderRefund issues a full or partial refund without cancelling the order
+func handleAdminOrderRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	o, ok := getOrder(r.FormValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if orderLocked(o.ID) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+	amount, _ := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if remaining := refundableAmount(o.ID); amount <= 0 || amount > remaining+0.005 {
+		http.Error(w, fmt.Sprintf("Refund must be between RM0.01 and RM%.2f", remaining), http.StatusBadRequest)
+		return
+	}
+	if err := issueRefund(o.ID, amount, "Manager: "+r.FormValue("reason")); err != nil {
+		http.Error(w, "Refund failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redirectToAdminOrder(w, r, o.ID, fmt.Sprintf("Refunded RM%.2f", amount))
+}
+
+// handleAdminOrderCancel cancels and refunds the order (shown as VOID on the KDS)
+func handleAdminOrderCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	o, ok := getOrder(r.FormValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if orderLocked(o.ID) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+	if o.Status == StatusCancelled {
+		http.Error(w, "This order is already cancelled", http.StatusConflict)
+		return
+	}
+	cancelOrder(o.ID, "Manager: "+r.FormValue("reason"))
+	redirectToAdminOrder(w, r, o.ID, "Order cancelled")
+}
+
+// handleAdminOrderReceipt resends the receipt link, optionally to a corrected phone number
+func handleAdminOrderReceipt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	o, ok := getOrder(r.FormValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if phone := strings.TrimSpace(r.FormValue("phone")); phone != "" {
+		o.Phone = phone
+	}
+	if o.Phone == "" {
+		http.Error(w, "No phone number to send the receipt to", http.StatusBadRequest)
+		return
+	}
+
+	link := fmt.Sprintf("http://%s/order?id=%d", r.Host, o.ID)
+	if err := receiptSender.SendReceipt(o, link); err != nil {
+		http.Error(w, "Sending failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recordOrderEvent(o.ID, "receipt_sent", o.Phone)
+	redirectToAdminOrder(w, r, o.ID, "Receipt sent to "+o.Phone)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Option groups that change how an item is made, so their options get their own all-day line.
+// Sweetness and remarks are left to the individual tickets; any paid option counts too.
+var allDaySignificantGroups = map[string]bool{
+	"Add-ons":     true,
+	"Temperature": true,
+}
+
+type allDayLine struct {
+	Name     string
+	Category string
+	Variants map[string]int // significant modifiers -> count ("" = plain)
+	Total    int
+}
+
+// kitchenStations are the menu categories, one all-day station each
+func kitchenStations() []string {
+	var stations []string
+	rows, err := db.Query("SELECT name FROM categories ORDER BY name")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		stations = append(stations, name)
+	}
+	return stations
+}
+
+// modifierGroups maps each option ever ordered to its group, for lines placed before
+// modifiers were stored with the order
+func modifierGroups() map[string]string {
+	groups := map[string]string{}
+	rows, err := db.Query("SELECT DISTINCT option_name, group_name FROM order_item_options")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return groups
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, group string
+		rows.Scan(&name, &group)
+		groups[name] = group
+	}
+	return groups
+}
+
+// significantOptions extracts the all-day relevant modifiers of an order line
+func significantOptions(item OrderItem, groups map[string]string) string {
+	mods := item.Modifiers
+	if len(mods) == 0 {
+		for _, name := range item.ModifierNames() {
+			mods = append(mods, Modifier{Group: groups[name], Name: name, Price: addonPrices[name]})
+		}
+	}
+	var keep []string
+	for _, m := range mods {
+		if allDaySignificantGroups[m.Group] || m.Price != 0 {
+			keep = append(keep, m.Name)
+		}
+	}
+	return strings.Join(keep, " + ")
+}
+
+// renderAllDayPanel shows how many of each item are on the board right now,
+// optionally limited to one station (category).
+func renderAllDayPanel(w http.ResponseWriter, orders []Order, station string) {
+	categories := map[string]string{}
+	rows, err := db.Query("SELECT name, category FROM products")
+	if err == nil {
+		for rows.Next() {
+			var name, cat string
+			rows.Scan(&name, &cat)
+			categories[name] = cat
+		}
+		rows.Close()
+	}
+
+	groups := modifierGroups()
+	lines := map[string]*allDayLine{}
+	for _, o := range orders {
+		if o.Status == StatusCancelled {
+			continue
+		}
+		for _, item := range o.Items {
+			if item.Voided {
+				continue
+			}
+			cat := categories[item.Name]
+			if station != "" && cat != station {
+				continue
+			}
+			line, ok := lines[item.Name]
+			if !ok {
+				line = &allDayLine{Name: item.Name, Category: cat, Variants: map[string]int{}}
+				lines[item.Name] = line
+			}
+			line.Variants[significantOptions(item, groups)]++
+			line.Total++
+		}
+	}
+
+	sorted := make([]*allDayLine, 0, len(lines))
+	for _, line := range lines {
+		sorted = append(sorted, line)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Total != sorted[j].Total {
+			return sorted[i].Total > sorted[j].Total
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	title := "All Day"
+	if station != "" {
+		title += " · " + strings.ToUpper(station)
+	}
+	fmt.Fprintf(w, `<div class="all-day"><span class="all-day-title">Σ %s</span>`, title)
+	if len(sorted) == 0 {
+		fmt.Fprint(w, `<span class="all-day-empty">Nothing on the board</span>`)
+	}
+	for _, line := range sorted {
+		var variants []string
+		for v, n := range line.Variants {
+			if v == "" {
+				v = "plain"
+			}
+			variants = append(variants, fmt.Sprintf("%dx %s", n, v))
+		}
+		sort.Strings(variants)
+
+		detail := ""
+		if len(line.Variants) > 1 || line.Variants[""] == 0 {
+			detail = fmt.Sprintf(`<small>%s</small>`, strings.Join(variants, ", "))
+		}
+		fmt.Fprintf(w, `<div class="all-day-item"><b>%dx</b> %s %s</div>`, line.Total, line.Name, detail)
+	}
+	fmt.Fprint(w, `</div>`)
+}
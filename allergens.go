@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Allergens tracked on products and add-ons, as stored in the comma-separated allergens columns
+var allergenList = []string{"nuts", "seafood", "dairy"}
+
+var allergenIcons = map[string]string{
+	"nuts":    "🥜",
+	"seafood": "🦐",
+	"dairy":   "🥛",
+}
+
+// Spicy levels 0-3
+var spicyLabels = []string{"Not spicy", "Mild", "Medium", "Hot"}
+
+// DietaryInfo is what a customer with an allergy or diet needs to know about an item
+type DietaryInfo struct {
+	Allergens  []string
+	Vegetarian bool
+	Spicy      int
+}
+
+// parseAllergens reads a stored allergens column, keeping only known allergens
+func parseAllergens(s string) []string {
+	var out []string
+	for _, a := range allergenList {
+		if strings.Contains(","+s+",", ","+a+",") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// cleanAllergens turns submitted checkbox values into the stored column value
+func cleanAllergens(values []string) string {
+	return strings.Join(parseAllergens(strings.Join(values, ",")), ",")
+}
+
+func (d DietaryInfo) Contains(allergen string) bool {
+	for _, a := range d.Allergens {
+		if a == allergen {
+			return true
+		}
+	}
+	return false
+}
+
+// Icons renders the allergen, vegetarian and chilli icons, each with a tooltip
+func (d DietaryInfo) Icons() string {
+	var icons []string
+	if d.Vegetarian {
+		icons = append(icons, `<span title="Vegetarian">🌱</span>`)
+	}
+	for _, a := range d.Allergens {
+		icons = append(icons, fmt.Sprintf(`<span title="Contains %s">%s</span>`, a, allergenIcons[a]))
+	}
+	if d.Spicy > 0 && d.Spicy < len(spicyLabels) {
+		icons = append(icons, fmt.Sprintf(`<span title="%s">%s</span>`, spicyLabels[d.Spicy], strings.Repeat("🌶️", d.Spicy)))
+	}
+	return strings.Join(icons, " ")
+}
+
+// dietaryBadges is the icon row on a customer menu card
+func dietaryBadges(d DietaryInfo) string {
+	icons := d.Icons()
+	if icons == "" {
+		return ""
+	}
+	return `<p class="mt-2 text-sm space-x-1">` + icons + `</p>`
+}
+
+// modifierDietary returns the allergens of every add-on that has them recorded
+func modifierDietary() map[string]DietaryInfo {
+	out := map[string]DietaryInfo{}
+	rows, err := db.Query("SELECT modifier_name, COALESCE(allergens, ''), COALESCE(vegetarian, 1) FROM modifier_attributes")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, allergens string
+		var d DietaryInfo
+		rows.Scan(&name, &allergens, &d.Vegetarian)
+		d.Allergens = parseAllergens(allergens)
+		out[name] = d
+	}
+	return out
+}
+
+// menuFilter is the customer's dietary filter on the menu, alongside the q search
+type menuFilter struct {
+	FreeFrom   []string // hide items containing any of these allergens
+	Vegetarian bool
+	MaxSpicy   int // -1 = any
+}
+
+func menuFilterFrom(q url.Values) menuFilter {
+	f := menuFilter{FreeFrom: parseAllergens(strings.Join(q["free_from"], ",")), MaxSpicy: -1}
+	f.Vegetarian = q.Get("vegetarian") == "on"
+	if v, err := strconv.Atoi(q.Get("max_spicy")); err == nil {
+		f.MaxSpicy = v
+	}
+	return f
+}
+
+// Allows reports whether an item passes the filter
+func (f menuFilter) Allows(d DietaryInfo) bool {
+	for _, a := range f.FreeFrom {
+		if d.Contains(a) {
+			return false
+		}
+	}
+	if f.Vegetarian && !d.Vegetarian {
+		return false
+	}
+	return f.MaxSpicy < 0 || d.Spicy <= f.MaxSpicy
+}
+
+// dietaryInputs renders the allergen/vegetarian/spicy controls for an admin form
+func dietaryInputs(d DietaryInfo, withSpicy bool) string {
+	var b strings.Builder
+	b.WriteString(`<div class="flex flex-wrap items-center gap-2 text-xs text-gray-600">`)
+	for _, a := range allergenList {
+		checked := ""
+		if d.Contains(a) {
+			checked = "checked"
+		}
+		fmt.Fprintf(&b, `<label class="flex items-center gap-1"><input type="checkbox" name="allergens" value="%s" %s> %s %s</label>`, a, checked, allergenIcons[a], a)
+	}
+	checked := ""
+	if d.Vegetarian {
+		checked = "checked"
+	}
+	fmt.Fprintf(&b, `<label class="flex items-center gap-1"><input type="checkbox" name="vegetarian" %s> 🌱 veg</label>`, checked)
+	if withSpicy {
+		b.WriteString(`<select name="spicy_level" class="border border-dashed border-gray-300 rounded bg-transparent">`)
+		for level, label := range spicyLabels {
+			selected := ""
+			if level == d.Spicy {
+				selected = "selected"
+			}
+			fmt.Fprintf(&b, `<option value="%d" %s>%s</option>`, level, selected, label)
+		}
+		b.WriteString(`</select>`)
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// renderModifierDietarySection lets the admin record allergens on add-ons
+func renderModifierDietarySection(w http.ResponseWriter) {
+	dietary := modifierDietary()
+	fmt.Fprint(w, `
+	<section>
+		<h2 class="text-2xl font-bold mb-6 text-gray-800 border-b pb-2">ADD-ON ALLERGENS</h2>
+		<div class="bg-white rounded-lg shadow-sm divide-y">`)
+	for _, name := range knownModifiers() {
+		d, ok := dietary[name]
+		if !ok {
+			d.Vegetarian = true
+		}
+		fmt.Fprintf(w, `
+			<form method="post" action="/admin/modifiers/dietary" class="flex flex-wrap items-center gap-4 p-3">
+				<input type="hidden" name="name" value="%s">
+				<span class="font-semibold w-40">%s</span>
+				%s
+				<button class="ml-auto text-sm bg-gray-900 text-white rounded px-3 py-1">Save</button>
+			</form>`, html.EscapeString(name), html.EscapeString(name), dietaryInputs(d, false))
+	}
+	fmt.Fprint(w, `
+		</div>
+	</section>`)
+}
+
+func handleAdminModifierDietary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	_, err := db.Exec(`INSERT INTO modifier_attributes (modifier_name, allergens, vegetarian) VALUES (?, ?, ?)
+		ON CONFLICT(modifier_name) DO UPDATE SET allergens = excluded.allergens, vegetarian = excluded.vegetarian`,
+		r.FormValue("name"), cleanAllergens(r.Form["allergens"]), r.FormValue("vegetarian") == "on")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// allergyBanner highlights the customer's allergy note on a kitchen ticket
+func allergyBanner(o Order) string {
+	if o.Allergy == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<div class="allergy-banner">⚠️ ALLERGY: %s</div>`, html.EscapeString(o.Allergy))
+}
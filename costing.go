@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// Theoretical food cost from recipes and ingredient costs (prices exclude tax)
+
+// loadRecipeCosts returns the cost of one unit of every product and modifier that has a recipe
+func loadRecipeCosts() (products map[int]float64, modifiers map[string]float64) {
+	products, modifiers = map[int]float64{}, map[string]float64{}
+	rows, err := db.Query(`
+		SELECT COALESCE(ri.product_id, 0), COALESCE(ri.modifier_name, ''), SUM(ri.quantity * COALESCE(i.cost_per_unit, 0))
+		FROM recipe_items ri JOIN ingredients i ON i.id = ri.ingredient_id
+		GROUP BY ri.product_id, ri.modifier_name`)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int
+		var modifier string
+		var cost float64
+		rows.Scan(&productID, &modifier, &cost)
+		if productID > 0 {
+			products[productID] = cost
+		} else {
+			modifiers[modifier] = cost
+		}
+	}
+	return
+}
+
+// foodCostPct is cost as a share of the selling price
+func foodCostPct(cost, price float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	return cost / price * 100
+}
+
+// productFoodCost is the cost of one serving of a product; ok is false when it has no recipe
+func productFoodCost(productID int) (cost float64, ok bool) {
+	var lines int
+	db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(ri.quantity * COALESCE(i.cost_per_unit, 0)), 0)
+		FROM recipe_items ri JOIN ingredients i ON i.id = ri.ingredient_id
+		WHERE ri.product_id = ?`, productID).Scan(&lines, &cost)
+	return cost, lines > 0
+}
+
+// costLineHTML is the food cost / margin summary shown under the price on the admin card
+func costLineHTML(p Product) string {
+	cost, ok := productFoodCost(p.ID)
+	if !ok {
+		return `<div class="text-xs text-gray-400">No recipe — <a href="/admin/inventory" class="underline">add one</a> to see food cost</div>`
+	}
+	pct := foodCostPct(cost, p.Price)
+	color := "text-green-700"
+	if pct > 35 {
+		color = "text-red-600"
+	}
+	return fmt.Sprintf(`<div class="text-xs %s">Food cost RM%.2f (%.0f%%) · Margin RM%.2f</div>`, color, cost, pct, p.Price-cost)
+}
+
+type menuItemStats struct {
+	ProductID int
+	Name      string
+	Qty       int
+	Revenue   float64
+	Cost      float64
+	Class     string
+}
+
+// Margin is the average contribution margin per item sold
+func (m menuItemStats) Margin() float64 {
+	if m.Qty == 0 {
+		return 0
+	}
+	return (m.Revenue - m.Cost) / float64(m.Qty)
+}
+
+// Menu engineering quadrants
+var menuClasses = map[string]string{
+	"Star":      "⭐ Star — popular and profitable, keep it prominent",
+	"Plowhorse": "🐴 Plowhorse — popular but low margin, review price or recipe",
+	"Puzzle":    "🧩 Puzzle — profitable but rarely ordered, promote it",
+	"Dog":       "🐶 Dog — unpopular and low margin, consider removing",
+}
+
+// menuEngineering classifies every product sold in the range by popularity and margin.
+// Popularity threshold is the usual 70% of an equal share of items sold; the margin
+// threshold is the weighted average margin across the menu.
+func menuEngineering(rr reportRange) []menuItemStats {
+	productCosts, modifierCosts := loadRecipeCosts()
+
+	stats := map[int]*menuItemStats{}
+	rows, err := db.Query(`
+		SELECT COALESCE(oi.product_id, 0), oi.product_name, COUNT(*), SUM(oi.price)
+		FROM order_items oi JOIN orders o ON o.id = oi.order_id
+		WHERE `+reportOrderFilter+` AND COALESCE(oi.voided, 0) = 0
+		GROUP BY oi.product_id, oi.product_name`, rr.From, rr.To)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	for rows.Next() {
+		var s menuItemStats
+		rows.Scan(&s.ProductID, &s.Name, &s.Qty, &s.Revenue)
+		s.Cost = productCosts[s.ProductID] * float64(s.Qty)
+		if existing, ok := stats[s.ProductID]; ok {
+			existing.Qty += s.Qty
+			existing.Revenue += s.Revenue
+			existing.Cost += s.Cost
+			continue
+		}
+		stats[s.ProductID] = &s
+	}
+	rows.Close()
+
+	// Modifiers add their own cost to the line they were sold on
+	rows, err = db.Query(`
+		SELECT COALESCE(oi.product_id, 0), oio.option_name, COUNT(*)
+		FROM order_item_options oio
+		JOIN order_items oi ON oi.id = oio.order_item_id
+		JOIN orders o ON o.id = oi.order_id
+		WHERE `+reportOrderFilter+` AND COALESCE(oi.voided, 0) = 0
+		GROUP BY oi.product_id, oio.option_name`, rr.From, rr.To)
+	if err == nil {
+		for rows.Next() {
+			var productID, n int
+			var option string
+			rows.Scan(&productID, &option, &n)
+			if s, ok := stats[productID]; ok {
+				s.Cost += modifierCosts[option] * float64(n)
+			}
+		}
+		rows.Close()
+	}
+
+	var out []menuItemStats
+	totalQty := 0
+	totalProfit := 0.0
+	for _, s := range stats {
+		totalQty += s.Qty
+		totalProfit += s.Revenue - s.Cost
+		out = append(out, *s)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	popularity := 0.7 * float64(totalQty) / float64(len(out))
+	avgMargin := totalProfit / float64(totalQty)
+
+	for i := range out {
+		popular := float64(out[i].Qty) >= popularity
+		profitable := out[i].Margin() >= avgMargin
+		switch {
+		case popular && profitable:
+			out[i].Class = "Star"
+		case popular:
+			out[i].Class = "Plowhorse"
+		case profitable:
+			out[i].Class = "Puzzle"
+		default:
+			out[i].Class = "Dog"
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Qty > out[j].Qty })
+	return out
+}
+
+// handleAdminMenuEngineering shows product and modifier margins with the menu-engineering matrix
+func handleAdminMenuEngineering(w http.ResponseWriter, r *http.Request) {
+	rr := reportRangeFrom(r.URL.Query())
+	items := menuEngineering(rr)
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="menu_engineering_%s_%s.csv"`, rr.From, rr.To))
+		out := csv.NewWriter(w)
+		out.Write([]string{"product", "qty", "revenue", "food_cost", "food_cost_pct", "margin_per_item", "class"})
+		for _, s := range items {
+			out.Write([]string{s.Name, strconv.Itoa(s.Qty), fmt.Sprintf("%.2f", s.Revenue), fmt.Sprintf("%.2f", s.Cost),
+				fmt.Sprintf("%.1f", foodCostPct(s.Cost, s.Revenue)), fmt.Sprintf("%.2f", s.Margin()), s.Class})
+		}
+		out.Flush()
+		return
+	}
+
+	adminPageStart(w, "Menu Engineering")
+	csvQuery := url.Values{"from": {rr.From}, "to": {rr.To}, "format": {"csv"}}
+	fmt.Fprintf(w, `
+    <form method="get" class="bg-white rounded-lg shadow p-4 flex flex-wrap items-end gap-4 text-sm">
+        <label class="flex flex-col">From <input type="date" name="from" value="%s" class="border rounded px-2 py-1"></label>
+        <label class="flex flex-col">To <input type="date" name="to" value="%s" class="border rounded px-2 py-1"></label>
+        <button class="bg-gray-900 text-white px-4 py-1.5 rounded font-semibold">Update</button>
+        <a href="/admin/reports/menu?%s" class="ml-auto text-blue-600 hover:underline">⬇ Export CSV</a>
+    </form>
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">Products</h2>
+        <table class="w-full text-sm">
+            <thead class="text-left text-gray-500"><tr><th>Product</th><th>Sold</th><th>Revenue</th><th>Food cost</th><th>Cost %%</th><th>Margin / item</th><th>Class</th></tr></thead><tbody>`,
+		html.EscapeString(rr.From), html.EscapeString(rr.To), html.EscapeString(csvQuery.Encode()))
+	for _, s := range items {
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 font-semibold">%s</td><td>%d</td><td>RM%.2f</td><td>RM%.2f</td><td>%.0f%%</td><td>RM%.2f</td><td title="%s">%s</td></tr>`,
+			html.EscapeString(s.Name), s.Qty, s.Revenue, s.Cost, foodCostPct(s.Cost, s.Revenue), s.Margin(), menuClasses[s.Class], s.Class)
+	}
+	if len(items) == 0 {
+		fmt.Fprint(w, `<tr><td colspan="7" class="py-6 text-center text-gray-400">No sales in this range</td></tr>`)
+	}
+	fmt.Fprint(w, `</tbody></table><ul class="mt-4 text-xs text-gray-500 space-y-1">`)
+	for _, class := range []string{"Star", "Plowhorse", "Puzzle", "Dog"} {
+		fmt.Fprintf(w, `<li>%s</li>`, menuClasses[class])
+	}
+	fmt.Fprint(w, `</ul></section>`)
+
+	// Paid add-ons against what goes into them
+	_, modifierCosts := loadRecipeCosts()
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">Add-ons</h2>
+        <table class="w-full text-sm">
+            <thead class="text-left text-gray-500"><tr><th>Add-on</th><th>Price</th><th>Food cost</th><th>Margin</th></tr></thead><tbody>`)
+	var addons []string
+	for name := range addonPrices {
+		addons = append(addons, name)
+	}
+	sort.Strings(addons)
+	for _, name := range addons {
+		cost, ok := modifierCosts[name]
+		costText := "no recipe"
+		if ok {
+			costText = fmt.Sprintf("RM%.2f (%.0f%%)", cost, foodCostPct(cost, addonPrices[name]))
+		}
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>RM%.2f</td><td>%s</td><td>RM%.2f</td></tr>`,
+			name, addonPrices[name], costText, addonPrices[name]-cost)
+	}
+	fmt.Fprint(w, `</tbody></table></section>`)
+	adminPageEnd(w)
+}
+
+// handleAdminIngredientCost sets an ingredient's purchase cost per unit
+func handleAdminIngredientCost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	cost, err := strconv.ParseFloat(r.FormValue("cost"), 64)
+	if err != nil || cost < 0 {
+		http.Error(w, "Enter a cost", http.StatusBadRequest)
+		return
+	}
+	if _, err := db.Exec("UPDATE ingredients SET cost_per_unit = ? WHERE id = ?", cost, id); err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Could not update cost", http.StatusInternalServerError)
+		return
+	}
+	redirectToInventory(w, r, "Cost updated")
+}
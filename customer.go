@@ -1,11 +1,17 @@
 package main
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"html"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -19,24 +25,58 @@ type Product struct {
 	ImageURL    string
 	TypeTag     string
 	InStock     bool
+	DailyCap    int // 0 = unlimited
+	SoldToday   int // only loaded for capped products on the menu
+	SearchTags  string
+	Dietary     DietaryInfo
+	Nutrition   Nutrition
+
+	// Search results only: name and description with the matched words highlighted
+	NameHTML        string
+	DescriptionHTML string
 }
 
 type CartItem struct {
-	Name       string
-	BasePrice  float64
-	AddonTotal float64
-	Options    []string
-	Remarks    string // <--- Add this field
+	ProductID int
+	Name      string
+	BasePrice float64
+	Options   []Modifier
+	Remarks   string // <--- Add this field
 
 }
 
-func (c CartItem) Total() float64 { return c.BasePrice + c.AddonTotal }
+func (c CartItem) Total() float64 {
+	total := c.BasePrice
+	for _, m := range c.Options {
+		total += m.Price
+	}
+	return total
+}
 
 var db *sql.DB
 var cart []CartItem
 
 const taxRate = 0.05
 
+// Accepted payment methods, as stored in orders.payment_method
+var paymentMethods = []string{"card", "e-wallet", "cash"}
+
+func validPaymentMethod(method string) bool {
+	for _, m := range paymentMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Paid add-ons and their price on top of the base price
+var addonPrices = map[string]float64{
+	"Extra Cheese":  3.0,
+	"Extra Topping": 5.0,
+	"Extra Pasta":   3.0,
+}
+
 // handleGetMenu generates the grid of products
 // handleGetMenu generates the grid of products with optional search filtering
 func handleGetMenu(w http.ResponseWriter, r *http.Request) {
@@ -44,23 +84,18 @@ func handleGetMenu(w http.ResponseWriter, r *http.Request) {
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	query = strings.ToLower(query)
 
-	var rows *sql.Rows
-	var err error
-
-	// 2. Fetch data based on search status
+	// 2. Search ranks the matching products (best first); without a query everything is listed
+	var ranked []int
+	var highlight []string
 	if query != "" {
-		// Search Mode: Filter by name or description
-		sqlQuery := `
-			SELECT id, category, name, description, price, image_url, type_tag, in_stock 
-			FROM products 
-			WHERE (LOWER(name) LIKE ? OR LOWER(description) LIKE ?)`
-		wildcard := "%" + query + "%"
-		rows, err = db.Query(sqlQuery, wildcard, wildcard)
-	} else {
-		// Default Mode: Fetch all
-		rows, err = db.Query("SELECT id, category, name, description, price, image_url, type_tag, in_stock FROM products")
+		ranked, highlight = menuSearch(query)
+	}
+	rank := map[int]int{}
+	for i, id := range ranked {
+		rank[id] = i
 	}
 
+	rows, err := db.Query("SELECT id, category, name, description, price, image_url, type_tag, in_stock, COALESCE(daily_cap, 0), COALESCE(allergens, ''), COALESCE(vegetarian, 0), COALESCE(spicy_level, 0) FROM products")
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
@@ -71,9 +106,36 @@ func handleGetMenu(w http.ResponseWriter, r *http.Request) {
 	categories := map[string][]Product{}
 	totalFound := 0
 
+	sold := soldToday(businessDay(time.Now()))
+	schedule := loadMenuSchedule()
+	tr := loadTranslator(requestLang(r))
+	filter := menuFilterFrom(r.URL.Query())
+	nutrition := productNutrition()
 	for rows.Next() {
 		var p Product
-		rows.Scan(&p.ID, &p.Category, &p.Name, &p.Description, &p.Price, &p.ImageURL, &p.TypeTag, &p.InStock)
+		var allergens string
+		rows.Scan(&p.ID, &p.Category, &p.Name, &p.Description, &p.Price, &p.ImageURL, &p.TypeTag, &p.InStock, &p.DailyCap,
+			&allergens, &p.Dietary.Vegetarian, &p.Dietary.Spicy)
+		p.Dietary.Allergens = parseAllergens(allergens)
+		p.Name = tr.ProductName(p.ID, p.Name)
+		p.Description = tr.ProductDescription(p.ID, p.Description)
+		// Scheduled items only appear inside their availability windows
+		if !schedule.Available(p.ID, p.Category, time.Now()) {
+			continue
+		}
+		// Dietary filters chosen next to the search box
+		if !filter.Allows(p.Dietary) {
+			continue
+		}
+		if query != "" {
+			if _, ok := rank[p.ID]; !ok {
+				continue
+			}
+			p.NameHTML = highlightMatches(p.Name, highlight)
+			p.DescriptionHTML = highlightMatches(p.Description, highlight)
+		}
+		p.SoldToday = sold[p.ID]
+		p.Nutrition = nutrition[p.ID]
 		categories[p.Category] = append(categories[p.Category], p)
 		totalFound++
 	}
@@ -85,11 +147,11 @@ func handleGetMenu(w http.ResponseWriter, r *http.Request) {
 				<div class="bg-gray-50 rounded-full h-16 w-16 flex items-center justify-center mx-auto mb-4">
 					<svg class="w-8 h-8 text-gray-400" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M21 21l-6-6m2-5a7 7 0 11-14 0 7 7 0 0114 0z"></path></svg>
 				</div>
-				<h3 class="text-lg font-medium text-gray-900">No matching items</h3>
-				<p class="text-gray-500 mt-1">Try searching for something else like "Pizza" or "Coffee"</p>
-				<button onclick="document.querySelector('input[name=q]').value = ''; htmx.trigger('input[name=q]', 'search')" class="mt-4 text-brand font-bold hover:underline">Clear Search</button>
+				<h3 class="text-lg font-medium text-gray-900">%s</h3>
+				<p class="text-gray-500 mt-1">%s</p>
+				<button onclick="document.querySelector('input[name=q]').value = ''; htmx.trigger('input[name=q]', 'search')" class="mt-4 text-brand font-bold hover:underline">%s</button>
 			</div>
-		`)
+		`, tr.T("No matching items"), html.EscapeString(tr.T(`Try searching for something else like "Pizza" or "Coffee"`)), tr.T("Clear Search"))
 		return
 	}
 
@@ -97,6 +159,20 @@ func handleGetMenu(w http.ResponseWriter, r *http.Request) {
 	// Add other categories to this list as needed
 	order := []string{"pizza", "pasta", "drink", "coffee", "dessert"}
 
+	// Search results: best matches first, and the category with the best match on top
+	if query != "" {
+		for _, products := range categories {
+			sort.SliceStable(products, func(i, j int) bool { return rank[products[i].ID] < rank[products[j].ID] })
+		}
+		bestRank := func(cat string) int {
+			if products := categories[cat]; len(products) > 0 {
+				return rank[products[0].ID]
+			}
+			return len(rank)
+		}
+		sort.SliceStable(order, func(i, j int) bool { return bestRank(order[i]) < bestRank(order[j]) })
+	}
+
 	for _, cat := range order {
 		products := categories[cat]
 		if len(products) == 0 {
@@ -111,40 +187,52 @@ func handleGetMenu(w http.ResponseWriter, r *http.Request) {
 					<div class="h-1 bg-gray-200 flex-grow rounded-full"></div>
 				</div>
 				<div class='grid grid-cols-1 sm:grid-cols-2 lg:grid-cols-2 xl:grid-cols-2 gap-6'>`,
-			cat, strings.ToUpper(cat))
+			cat, html.EscapeString(strings.ToUpper(tr.Category(cat))))
 
 		for _, p := range products {
-			renderProductCard(w, p)
+			renderProductCard(w, p, tr)
 		}
 		fmt.Fprintf(w, "</div></section>")
 	}
 }
 
-// renderProductCard generates the HTML for a single item card
-func renderProductCard(w http.ResponseWriter, p Product) {
+// renderProductCard generates the HTML for a single item card, in the translator's language
+func renderProductCard(w http.ResponseWriter, p Product, tr translator) {
 	optionsHTML := ""
 
 	// Reusable styling for option chips using Tailwind
 	// Note: We use has-[:checked] to style the label based on the hidden input state
 	radioStyle := `class="cursor-pointer border border-gray-200 rounded-full px-3 py-1 text-xs font-medium text-gray-600 bg-white shadow-sm hover:bg-gray-50 has-[:checked]:bg-orange-50 has-[:checked]:text-brand has-[:checked]:border-brand transition-all select-none"`
 
+	// Add-on chips carry their own allergen icons
+	addonDietary := modifierDietary()
+
+	// Chips keep their short English text; other languages show the add-on's translated name
+	chip := func(modifier, short string) string {
+		if tr.Lang == defaultLang {
+			return short
+		}
+		return html.EscapeString(tr.Modifier(modifier))
+	}
+
 	switch p.TypeTag {
 	case "pizza_opt":
 		optionsHTML = fmt.Sprintf(`
 			<div class="mt-3 space-y-2">
-				<p class="text-xs font-bold text-gray-500 uppercase">Add-ons</p>
+				<p class="text-xs font-bold text-gray-500 uppercase">%s</p>
 				<div class="flex flex-wrap gap-2">
-					<label %s><input type="checkbox" name="extra_cheese" class="hidden"><span>🧀 Ex. Cheese</span></label>
-					<label %s><input type="checkbox" name="extra_topping" class="hidden"><span>🍕 Ex. Topping</span></label>
+					<label %s><input type="checkbox" name="extra_cheese" class="hidden"><span>🧀 %s %s</span></label>
+					<label %s><input type="checkbox" name="extra_topping" class="hidden"><span>🍕 %s %s</span></label>
 				</div>
-			</div>`, radioStyle, radioStyle)
+			</div>`, tr.T("Add-ons"), radioStyle, chip("Extra Cheese", "Ex. Cheese"), addonDietary["Extra Cheese"].Icons(),
+			radioStyle, chip("Extra Topping", "Ex. Topping"), addonDietary["Extra Topping"].Icons())
 	case "coffee_opt":
 		optionsHTML = fmt.Sprintf(`
 			<div class="mt-3 space-y-2">
 				<!-- Temp -->
 				<div class="flex gap-2">
-					<label %s><input type="radio" name="temp" value="Ice" checked class="hidden"><span>❄️ Ice</span></label>
-					<label %s><input type="radio" name="temp" value="Hot" class="hidden"><span>🔥 Hot</span></label>
+					<label %s><input type="radio" name="temp" value="Ice" checked class="hidden"><span>❄️ %s</span></label>
+					<label %s><input type="radio" name="temp" value="Hot" class="hidden"><span>🔥 %s</span></label>
 				</div>
 				<!-- Sweetness -->
 				<div class="flex flex-wrap gap-2">
@@ -152,34 +240,34 @@ func renderProductCard(w http.ResponseWriter, p Product) {
 					<label %s><input type="radio" name="sweetness" value="Less Sweet" class="hidden"><span>50%%</span></label>
 					<label %s><input type="radio" name="sweetness" value="Least Sweet" class="hidden"><span>0%%</span></label>
 				</div>
-			</div>`, radioStyle, radioStyle, radioStyle, radioStyle, radioStyle)
+			</div>`, radioStyle, chip("Ice", "Ice"), radioStyle, chip("Hot", "Hot"), radioStyle, radioStyle, radioStyle)
 	case "pasta_opt":
 		optionsHTML = fmt.Sprintf(`
 			<div class="mt-3">
-				<label %s><input type="checkbox" name="extra_pasta" class="hidden"><span>🍝 Extra Portion (+RM3)</span></label>
-			</div>`, radioStyle)
+				<label %s><input type="checkbox" name="extra_pasta" class="hidden"><span>🍝 %s (+RM3) %s</span></label>
+			</div>`, radioStyle, chip("Extra Pasta", "Extra Portion"), addonDietary["Extra Pasta"].Icons())
 	}
 
 	// Logic for Button and Availability
 	// Note: hx-target="#desktop-cart-status" targets the ID inside index.html.
 	// The javascript in index.html then syncs this to the mobile drawer.
 	btnClass := "w-full bg-brand hover:bg-brand-dark text-white font-bold py-2 px-4 rounded-lg shadow-md active:scale-95 transition-all flex justify-center items-center gap-2"
-	btnText := "Add to Order <span>+</span>"
+	btnText := tr.T("Add to Order") + " <span>+</span>"
 	cardOpacity := ""
 	disabledAttr := ""
 
 	if !p.InStock {
 		btnClass = "w-full bg-gray-200 text-gray-400 font-bold py-2 px-4 rounded-lg cursor-not-allowed"
-		btnText = "Sold Out"
+		btnText = tr.T("Sold Out")
 		cardOpacity = "opacity-60 grayscale"
 		disabledAttr = "disabled"
 	}
 
-	remarksInput := `
+	remarksInput := fmt.Sprintf(`
         <div class="mt-3">
-            <input type="text" name="remarks" placeholder="Add remark (e.g. no onions)..." 
+            <input type="text" name="remarks" placeholder="%s" 
             class="w-full text-xs border border-gray-200 bg-gray-50 rounded px-2 py-1.5 focus:outline-none focus:border-brand focus:bg-white transition-colors">
-        </div>`
+        </div>`, html.EscapeString(tr.T("Add remark (e.g. no onions)...")))
 
 	fmt.Fprintf(w, `
         <form hx-post="/cart/add?id=%d" hx-target="#desktop-cart-status" class="bg-white rounded-xl shadow border border-gray-100 overflow-hidden flex flex-col h-full hover:shadow-lg transition-shadow duration-300 %s">
@@ -195,7 +283,9 @@ func renderProductCard(w http.ResponseWriter, p Product) {
                     <h3 class="font-bold text-lg text-gray-800 leading-tight">%s</h3>
                     <p class="text-sm text-gray-500 mt-1 line-clamp-2">%s</p>
                     %s
+                    %s
                     %s  <!-- Inject remarksInput here -->
+                    %s
                 </div>
                 
                 <div class="mt-4 pt-4 border-t border-gray-50">
@@ -205,69 +295,96 @@ func renderProductCard(w http.ResponseWriter, p Product) {
                 </div>
             </div>
         </form>`,
-		p.ID, cardOpacity, p.ImageURL, p.Name, p.Price, p.Name, p.Description, optionsHTML, remarksInput, btnClass, disabledAttr, btnText)
+		p.ID, cardOpacity, p.ImageURL, p.Name, p.Price, orDefault(p.NameHTML, p.Name), orDefault(p.DescriptionHTML, p.Description), dietaryBadges(p.Dietary)+dailyCapBadge(p, tr), optionsHTML, remarksInput, nutritionDetails(p, p.Nutrition, tr), btnClass, disabledAttr, btnText)
 }
 
 func handleAddToCart(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	id := r.URL.Query().Get("id")
 	var p Product
-	err := db.QueryRow("SELECT name, price, category FROM products WHERE id = ?", id).Scan(&p.Name, &p.Price, &p.Category)
+	err := db.QueryRow("SELECT id, name, price, category, in_stock FROM products WHERE id = ?", id).Scan(&p.ID, &p.Name, &p.Price, &p.Category, &p.InStock)
 	if err != nil {
 		return
 	}
+	// The card may have been rendered before the kitchen 86'd the item or its window closed
+	if !p.InStock || !productScheduledNow(p.ID) {
+		renderCart(w, r)
+		return
+	}
 
-	item := CartItem{Name: p.Name, BasePrice: p.Price}
+	item := CartItem{ProductID: p.ID, Name: p.Name, BasePrice: p.Price}
 
 	// ADD THIS BLOCK: Capture Remarks
 	if remark := strings.TrimSpace(r.FormValue("remarks")); remark != "" {
 		item.Remarks = remark
 	}
 
+	item.Options = selectedModifiers(r)
+
+	cart = append(cart, item)
+	renderCart(w, r)
+}
+
+// selectedModifiers reads the options chosen on a menu card
+func selectedModifiers(r *http.Request) []Modifier {
+	var mods []Modifier
 	// Logic for Add-ons
 	if r.FormValue("extra_cheese") == "on" {
-		item.Options = append(item.Options, "Extra Cheese")
-		item.AddonTotal += 3.0
+		mods = append(mods, Modifier{"Add-ons", "Extra Cheese", addonPrices["Extra Cheese"]})
 	}
 	if r.FormValue("extra_topping") == "on" {
-		item.Options = append(item.Options, "Extra Topping")
-		item.AddonTotal += 5.0
+		mods = append(mods, Modifier{"Add-ons", "Extra Topping", addonPrices["Extra Topping"]})
 	}
 	if r.FormValue("extra_pasta") == "on" {
-		item.Options = append(item.Options, "Extra Pasta")
-		item.AddonTotal += 3.0
+		mods = append(mods, Modifier{"Add-ons", "Extra Pasta", addonPrices["Extra Pasta"]})
 	}
 	if sw := r.FormValue("sweetness"); sw != "" {
-		item.Options = append(item.Options, sw)
+		mods = append(mods, Modifier{"Sweetness", sw, 0})
 	}
 	if t := r.FormValue("temp"); t != "" {
-		item.Options = append(item.Options, t)
+		mods = append(mods, Modifier{"Temperature", t, 0})
 	}
-
-	cart = append(cart, item)
-	renderCart(w)
+	return mods
 }
 
 func handleClearCart(w http.ResponseWriter, r *http.Request) {
 	cart = []CartItem{}
-	renderCart(w)
+	renderCart(w, r)
+}
+
+// handleGetCart re-renders the cart, e.g. after the menu's stock changed
+func handleGetCart(w http.ResponseWriter, r *http.Request) {
+	renderCart(w, r)
 }
 
-func renderCart(w http.ResponseWriter) {
+// handleRemoveFromCart drops a single cart line by index (used for sold-out items)
+func handleRemoveFromCart(w http.ResponseWriter, r *http.Request) {
+	i, err := strconv.Atoi(r.URL.Query().Get("i"))
+	if err == nil && i >= 0 && i < len(cart) {
+		cart = append(cart[:i], cart[i+1:]...)
+	}
+	renderCart(w, r)
+}
+
+func renderCart(w http.ResponseWriter, r *http.Request) {
+	tr := loadTranslator(requestLang(r))
 	if len(cart) == 0 {
-		fmt.Fprint(w, `
+		fmt.Fprintf(w, `
 			<div class="flex flex-col items-center justify-center py-10 text-gray-400">
 				<svg class="w-12 h-12 mb-2 opacity-50" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M3 3h2l.4 2M7 13h10l4-8H5.4M7 13L5.4 5M7 13l-2.293 2.293c-.63.63-.184 1.707.707 1.707H17m0 0a2 2 0 100 4 2 2 0 000-4zm-8 2a2 2 0 11-4 0 2 2 0 014 0z"></path></svg>
-				<p>Your cart is empty.</p>
+				<p>%s</p>
 			</div>
-		`)
+		`, tr.T("Your cart is empty."))
 		return
 	}
 
 	subtotal := 0.0
+	soldOut := soldOutCartItems()
+	anySoldOut := false
+	productFacts, modifierFacts := productNutrition(), modifierNutrition()
 	fmt.Fprint(w, `<ul class="divide-y divide-gray-100 max-h-[50vh] overflow-y-auto mb-4 custom-scrollbar">`)
 
-	for _, item := range cart {
+	for i, item := range cart {
 		subtotal += item.Total()
 
 		// Build the display for options
@@ -275,13 +392,24 @@ func renderCart(w http.ResponseWriter) {
 		var metaParts []string
 
 		if len(item.Options) > 0 {
-			metaParts = append(metaParts, strings.Join(item.Options, ", "))
+			metaParts = append(metaParts, html.EscapeString(strings.Join(tr.Modifiers(modifierNames(item.Options)), ", ")))
+		}
+
+		// Calories for the line as configured
+		if n := lineNutrition(item.ProductID, item.Options, productFacts, modifierFacts); n.Known {
+			metaParts = append(metaParts, fmt.Sprintf("🥗 %.0f kcal", n.Calories))
 		}
 
 		// ADD THIS: Add remarks to display
 		if item.Remarks != "" {
 			// Styled with an italic font and a "Note:" prefix
-			metaParts = append(metaParts, fmt.Sprintf(`<span class="text-orange-600 italic">Note: %s</span>`, item.Remarks))
+			metaParts = append(metaParts, fmt.Sprintf(`<span class="text-orange-600 italic">%s %s</span>`, tr.T("Note:"), item.Remarks))
+		}
+
+		// Items 86'd by the kitchen after they were added must be removed before checkout
+		if soldOut[i] {
+			anySoldOut = true
+			metaParts = append(metaParts, fmt.Sprintf(`<span class="text-red-600 font-bold">%s — <button hx-post="/cart/remove?i=%d" hx-target="#desktop-cart-status" class="underline">%s</button></span>`, tr.T("Sold out"), i, tr.T("remove")))
 		}
 
 		if len(metaParts) > 0 {
@@ -295,77 +423,314 @@ func renderCart(w http.ResponseWriter) {
                     %s
                 </div>
                 <span class="font-bold text-gray-700 text-sm">RM%.2f</span>
-            </li>`, item.Name, displayMeta, item.Total())
+            </li>`, tr.ProductName(item.ProductID, item.Name), displayMeta, item.Total())
 	}
 
 	tax := subtotal * taxRate
 	total := subtotal + tax
+	waitMinutes := estimateWaitMinutes(cart)
+
+	// Online checkout can be paused by the kitchen or by load throttling
+	checkoutHTML := fmt.Sprintf(`
+			<form action="/checkout" method="post" class="space-y-2" onsubmit="this.querySelector('button[type=submit]').disabled = true">
+				<input type="hidden" name="checkout_key" value="%s">
+				<div class="flex gap-2">
+					<input type="text" name="customer_name" placeholder="%s" class="w-1/2 text-sm border border-gray-200 rounded px-2 py-1.5 focus:outline-none focus:border-brand">
+					<input type="tel" name="customer_phone" placeholder="%s" class="w-1/2 text-sm border border-gray-200 rounded px-2 py-1.5 focus:outline-none focus:border-brand">
+				</div>
+				<input type="text" name="allergy_note" maxlength="200" placeholder="%s" class="w-full text-sm border border-gray-200 rounded px-2 py-1.5 focus:outline-none focus:border-brand">
+				<select name="payment_method" class="w-full text-sm border border-gray-200 rounded px-2 py-1.5 bg-white focus:outline-none focus:border-brand">
+					<option value="card">%s</option>
+					<option value="e-wallet">%s</option>
+					<option value="cash">%s</option>
+				</select>
+				<button type="submit" class="w-full bg-gray-900 hover:bg-black text-white font-bold py-3 px-4 rounded-lg shadow-lg hover:shadow-xl transition-all transform active:scale-95 flex justify-center items-center gap-2">
+					<span>%s</span>
+					<svg class="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M14 5l7 7m0 0l-7 7m7-7H3"></path></svg>
+				</button>
+			</form>`, newCheckoutKey(), html.EscapeString(tr.T("Name (optional)")), html.EscapeString(tr.T("Phone (optional)")),
+		html.EscapeString(tr.T("⚠️ Food allergy? Tell the kitchen (e.g. peanuts)")),
+		tr.T("💳 Card"), tr.T("📱 E-wallet"), tr.T("💵 Cash at counter"), tr.T("Checkout & Pay"))
+	if open, msg := orderingStatus(requestChannel(r)); !open {
+		checkoutHTML = fmt.Sprintf(`
+			<div class="w-full bg-red-50 text-red-700 border border-red-200 text-sm font-medium py-3 px-4 rounded-lg text-center">⏸️ %s</div>`, msg)
+	} else if anySoldOut {
+		checkoutHTML = fmt.Sprintf(`
+			<div class="w-full bg-red-50 text-red-700 border border-red-200 text-sm font-medium py-3 px-4 rounded-lg text-center">🚫 %s</div>`, tr.T("Some items just sold out. Remove them to check out."))
+	}
 
 	fmt.Fprintf(w, `</ul>
 
 		<div class="bg-gray-50 rounded-lg p-4 space-y-2 border border-gray-100">
 			<div class="flex justify-between text-sm text-gray-600">
-				<span>Subtotal</span><span>RM%.2f</span>
+				<span>%s</span><span>RM%.2f</span>
 			</div>
 			<div class="flex justify-between text-sm text-gray-600">
-				<span>Tax (5%%)</span><span>RM%.2f</span>
+				<span>%s</span><span>RM%.2f</span>
 			</div>
 			<div class="flex justify-between text-lg font-bold text-gray-900 border-t border-gray-200 pt-2 mt-1">
-				<span>Total</span><span class="grand-total-value">RM%.2f</span>
+				<span>%s</span><span class="grand-total-value">RM%.2f</span>
 			</div>
 		</div>
 
+		%s
+
+		<div class="mt-3 text-center text-sm text-gray-600">
+			⏱️ %s
+		</div>
+
 		<div class="mt-6 space-y-3">
-			<form action="/checkout" method="post">
-				<button type="submit" class="w-full bg-gray-900 hover:bg-black text-white font-bold py-3 px-4 rounded-lg shadow-lg hover:shadow-xl transition-all transform active:scale-95 flex justify-center items-center gap-2">
-					<span>Checkout & Pay</span>
-					<svg class="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M14 5l7 7m0 0l-7 7m7-7H3"></path></svg>
-				</button>
-			</form>
+			%s
 			<button hx-post="/cart/clear" hx-target="#desktop-cart-status" 
 				class="w-full text-xs text-gray-400 hover:text-red-500 underline decoration-dotted transition-colors">
-				Clear Order
+				%s
 			</button>
-		</div>`, subtotal, tax, total)
+		</div>`, tr.T("Subtotal"), subtotal, tr.T("Tax (5%)"), tax, tr.T("Total"), total, cartNutritionHTML(cart, tr),
+		fmt.Sprintf(tr.T("Estimated ready in %s (around %s)"),
+			`<span class="font-bold text-gray-900">`+fmt.Sprintf(tr.T("~%d mins"), waitMinutes)+`</span>`, formatReadyTime(waitMinutes)),
+		checkoutHTML, tr.T("Clear Order"))
 }
 
 func handleCheckout(w http.ResponseWriter, r *http.Request) {
+	// A double-click or retry of the same cart submission returns the order it already created
+	checkoutKey := r.FormValue("checkout_key")
+	if orderID, ok := orderForCheckoutKey(checkoutKey); ok {
+		http.Redirect(w, r, fmt.Sprintf("/success?order=%d", orderID), http.StatusSeeOther)
+		return
+	}
+
 	if len(cart) == 0 {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
+	// Refuse online orders while the kitchen is paused or overloaded
+	channel := requestChannel(r)
+	if open, msg := orderingStatus(channel); !open {
+		renderCheckoutRefused(w, http.StatusServiceUnavailable, "⏸️", "We're catching up!", msg)
+		return
+	}
+
+	// Items whose availability window has closed since they were added
+	if off := unscheduledCartItems(); len(off) > 0 {
+		renderCheckoutRefused(w, http.StatusConflict, "⏰", "Not available right now", fmt.Sprintf("%s isn't on the menu at this time. Please remove it from your order and check out again.", strings.Join(off, ", ")))
+		return
+	}
+
+	// Promise a ready time based on the current kitchen load
+	readyAt := time.Now().UTC().Add(time.Duration(estimateWaitMinutes(cart)) * time.Minute)
+
+	customerName := strings.TrimSpace(r.FormValue("customer_name"))
+	if customerName == "" {
+		customerName = "Guest Customer"
+	}
+	customerPhone := strings.TrimSpace(r.FormValue("customer_phone"))
+	paymentMethod := r.FormValue("payment_method")
+	if !validPaymentMethod(paymentMethod) {
+		paymentMethod = "card"
+	}
+	allergyNote := strings.TrimSpace(r.FormValue("allergy_note"))
+
+	// Everything below is one transaction: either the whole order is saved or nothing is
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Re-check stock and prices against the menu; the cart may be stale
+	soldOut, repriced, err := revalidateCart(tx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(soldOut) > 0 {
+		renderCheckoutRefused(w, http.StatusConflict, "🚫", "Sorry, sold out!", fmt.Sprintf("%s just sold out. Please remove it from your order and check out again.", strings.Join(soldOut, ", ")))
+		return
+	}
+	if len(repriced) > 0 {
+		renderCheckoutRefused(w, http.StatusConflict, "🏷️", "Prices updated", fmt.Sprintf("The price of %s has changed since you added it. Please review your order and check out again.", strings.Join(repriced, ", ")))
+		return
+	}
+
 	total := 0.0
 	for _, item := range cart {
 		total += item.Total()
 	}
 	totalWithTax := total + (total * taxRate)
 
+	// Assign the short pickup number for today's sequence
+	day := businessDay(time.Now())
+	pickupNumber, err := nextPickupNumber(tx, day, channel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Nothing more can be sold once the day has been closed and reconciled. Checked after
+	// the first write so a close running at the same moment can't slip in between.
+	if dayClosedIn(tx, day) {
+		tx.Rollback()
+		renderCheckoutRefused(w, http.StatusServiceUnavailable, "🔒", "We're closed", "Today's sales have been closed off. Please come back tomorrow!")
+		return
+	}
+
 	// Save to DB
-	res, err := db.Exec("INSERT INTO orders (customer_name, total_amount, status) VALUES (?, ?, ?)", "Guest Customer", totalWithTax, "Paid")
+	res, err := tx.Exec("INSERT INTO orders (customer_name, customer_phone, total_amount, status, pickup_number, channel, business_day, estimated_ready_at, checkout_key, payment_method, allergy_note) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		customerName, customerPhone, totalWithTax, "Paid", pickupNumber, channel, day, readyAt.Format("2006-01-02 15:04:05"), nullIfEmpty(checkoutKey), paymentMethod, nullIfEmpty(allergyNote))
 	if err != nil {
+		// Lost the race against a concurrent submission of the same cart
+		tx.Rollback()
+		if orderID, ok := orderForCheckoutKey(checkoutKey); ok {
+			http.Redirect(w, r, fmt.Sprintf("/success?order=%d", orderID), http.StatusSeeOther)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	orderID, _ := res.LastInsertId()
 
 	for _, item := range cart {
-		// Combine Options and Remarks for storage
-		fullOptions := strings.Join(item.Options, ", ")
-		if item.Remarks != "" {
-			if fullOptions != "" {
-				fullOptions += " | "
-			}
-			fullOptions += "RMK: " + item.Remarks
-		}
-
-		_, err = db.Exec("INSERT INTO order_items (order_id, product_name, options, price) VALUES (?, ?, ?, ?)",
-			orderID, item.Name, fullOptions, item.Total())
+		// Modifiers are stored as rows; the combined text is kept for older screens
+		res, err := tx.Exec("INSERT INTO order_items (order_id, product_id, product_name, options, remarks, price) VALUES (?, ?, ?, ?, ?, ?)",
+			orderID, item.ProductID, item.Name, formatOptions(modifierNames(item.Options), item.Remarks), item.Remarks, item.Total())
 		if err != nil {
 			log.Printf("Error saving item: %v", err)
+			http.Error(w, "Could not save your order, please try again", http.StatusInternalServerError)
+			return
+		}
+		itemID, _ := res.LastInsertId()
+		if err := saveModifiers(tx, itemID, item.Options); err != nil {
+			log.Printf("Error saving item options: %v", err)
+			http.Error(w, "Could not save your order, please try again", http.StatusInternalServerError)
+			return
 		}
 	}
 
+	// Daily specials: checked now that this order's items are written, so concurrent checkouts can't oversell
+	over, err := overDailyCap(tx, day, cart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(over) > 0 {
+		tx.Rollback()
+		renderCheckoutRefused(w, http.StatusConflict, "🔥", "Almost gone!", fmt.Sprintf("Sorry, %s. Please update your order and check out again.", strings.Join(over, "; ")))
+		return
+	}
+	if err := refreshDailyCaps(tx, day); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Take the ingredients off the shelf and sell out anything that can't be made any more
+	short, err := shortOfStock(tx, cart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(short) > 0 {
+		tx.Rollback()
+		renderCheckoutRefused(w, http.StatusConflict, "🚫", "Sorry, sold out!", fmt.Sprintf("%s just sold out. Please remove it from your order and check out again.", strings.Join(short, ", ")))
+		return
+	}
+	if err := depleteStock(tx, orderID, cart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("INSERT INTO order_events (order_id, event, detail) VALUES (?, 'status', ?)", orderID, StatusPaid); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Redirect to success
-	http.Redirect(w, r, "/success", http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/success?order=%d", orderID), http.StatusSeeOther)
+}
+
+// orderForCheckoutKey finds the order already placed for a cart submission
+func orderForCheckoutKey(key string) (int64, bool) {
+	if key == "" {
+		return 0, false
+	}
+	var orderID int64
+	err := db.QueryRow("SELECT id FROM orders WHERE checkout_key = ?", key).Scan(&orderID)
+	return orderID, err == nil
+}
+
+// newCheckoutKey returns a random idempotency key for one rendering of the checkout form
+func newCheckoutKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// revalidateCart compares the cart with the menu inside the checkout transaction.
+// Lines whose price changed are updated in the cart so the customer can review them.
+func revalidateCart(tx *sql.Tx) (soldOut, repriced []string, err error) {
+	for i := range cart {
+		item := &cart[i]
+		var price float64
+		var inStock bool
+		err := tx.QueryRow("SELECT price, in_stock FROM products WHERE id = ?", item.ProductID).Scan(&price, &inStock)
+		if err == sql.ErrNoRows {
+			soldOut = append(soldOut, item.Name)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if !inStock {
+			soldOut = append(soldOut, item.Name)
+			continue
+		}
+
+		changed := price != item.BasePrice
+		item.BasePrice = price
+		for j, m := range item.Options {
+			if addon, ok := addonPrices[m.Name]; ok && addon != m.Price {
+				item.Options[j].Price = addon
+				changed = true
+			}
+		}
+		if changed {
+			repriced = append(repriced, item.Name)
+		}
+	}
+	return soldOut, repriced, nil
+}
+
+// renderCheckoutRefused is shown instead of the success page when checkout can't go ahead
+// (503 while the kitchen can't take orders, 409 when the cart itself needs changing)
+func renderCheckoutRefused(w http.ResponseWriter, status int, icon, title, msg string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html lang="en">
+		<head>
+			<meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0">
+			<script src="https://cdn.tailwindcss.com"></script>
+		</head>
+		<body class="bg-gray-50 flex items-center justify-center h-screen">
+			<div class="bg-white p-8 rounded-xl shadow-lg text-center max-w-md">
+				<div class="text-6xl mb-4">%s</div>
+				<h1 class="text-2xl font-bold text-gray-800 mb-2">%s</h1>
+				<p class="text-gray-600 mb-6">%s</p>
+				<a href='/' class="inline-block bg-orange-600 text-white px-6 py-2 rounded-lg font-medium hover:bg-orange-700 transition">Back to Menu</a>
+			</div>
+		</body>
+		</html>
+	`, icon, title, msg)
 }
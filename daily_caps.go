@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Limited daily specials: products with a daily_cap sell out once that many have been
+// ordered in the business day, and come back at the next business day.
+// Cancelled orders and voided items don't count, so their portions go back on sale.
+
+// dailySold counts today's portions of the product in the surrounding products row
+const dailySold = `(SELECT COUNT(*) FROM order_items oi JOIN orders o ON o.id = oi.order_id
+	WHERE oi.product_id = products.id AND o.business_day = ? AND o.status != 'Cancelled' AND COALESCE(oi.voided, 0) = 0)`
+
+// soldToday returns how many of each capped product have been ordered in the business day
+func soldToday(day string) map[int]int {
+	sold := map[int]int{}
+	rows, err := db.Query(`SELECT id, `+dailySold+` FROM products WHERE COALESCE(daily_cap, 0) > 0`, day)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return sold
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, n int
+		rows.Scan(&id, &n)
+		sold[id] = n
+	}
+	return sold
+}
+
+// refreshDailyCaps marks capped products that have hit their cap for the day and clears
+// the mark once they are under it again (a new day, a cancellation, a raised limit)
+func refreshDailyCaps(ex execer, day string) error {
+	if _, err := ex.Exec(`UPDATE products SET cap_sold_out = (COALESCE(daily_cap, 0) > 0 AND `+dailySold+` >= daily_cap)`, day); err != nil {
+		return err
+	}
+	return applyAvailability(ex)
+}
+
+// releaseDailyCaps puts capped portions freed by a void or cancellation back on sale
+func releaseDailyCaps() {
+	if err := refreshDailyCaps(db, businessDay(time.Now())); err != nil {
+		fmt.Println("Error refreshing daily caps:", err)
+	}
+}
+
+// overDailyCap is checked inside the checkout transaction after the order's items are written.
+// Holding the write lock by then means concurrent checkouts are serialised, so the count
+// includes every other committed order and no two carts can both take the last portion.
+// Returns a message per product the cart would push over its cap.
+func overDailyCap(tx *sql.Tx, day string, items []CartItem) ([]string, error) {
+	inCart := map[int]int{}
+	for _, item := range items {
+		inCart[item.ProductID]++
+	}
+
+	rows, err := tx.Query(`SELECT id, name, daily_cap, `+dailySold+` AS sold FROM products
+		WHERE COALESCE(daily_cap, 0) > 0 AND sold > daily_cap`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var over []string
+	for rows.Next() {
+		var id, dailyCap, sold int
+		var name string
+		rows.Scan(&id, &name, &dailyCap, &sold)
+		left := dailyCap - (sold - inCart[id])
+		if left <= 0 {
+			over = append(over, fmt.Sprintf("%s has sold out for today", name))
+		} else {
+			over = append(over, fmt.Sprintf("only %d %s left today", left, name))
+		}
+	}
+	return over, rows.Err()
+}
+
+// dailyCapBadge is the "Only N left" note on a capped product's menu card
+func dailyCapBadge(p Product, tr translator) string {
+	if p.DailyCap <= 0 || !p.InStock {
+		return ""
+	}
+	left := p.DailyCap - p.SoldToday
+	if left <= 0 {
+		return ""
+	}
+	return `<p class="mt-2 inline-block bg-red-50 text-red-600 text-xs font-bold px-2 py-0.5 rounded-full">` + fmt.Sprintf(tr.T("🔥 Only %d left today"), left) + `</p>`
+}
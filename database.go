@@ -2,7 +2,9 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"strings"
 )
 
 func initDB(db *sql.DB) {
@@ -22,12 +24,15 @@ func initDB(db *sql.DB) {
 		in_stock BOOLEAN
 	)`)
 
+	// Business day a product was 86'd from the KDS with auto-restore (NULL = stays sold out)
+	addColumn(db, "products", "sold_out_day TEXT")
+
 	// 1. Create ORDERS Table
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS orders (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		customer_name TEXT,
 		total_amount REAL,
-		status TEXT DEFAULT 'Paid', -- Paid, Completed
+		status TEXT DEFAULT 'Paid', -- Paid, Completed, Picked Up, Cancelled
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	)`)
 
@@ -41,6 +46,239 @@ func initDB(db *sql.DB) {
 		FOREIGN KEY(order_id) REFERENCES orders(id)
 	)`)
 
+	// Key/value settings changed at runtime (e.g. online ordering pause, business day start hour)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT
+	)`)
+
+	// Pickup numbers: short per-day ticket numbers shown to customers instead of orders.id
+	addColumn(db, "orders", "pickup_number TEXT")
+	addColumn(db, "orders", "channel TEXT DEFAULT 'web'")
+	addColumn(db, "orders", "business_day TEXT")
+	db.Exec("UPDATE orders SET business_day = date(created_at, 'localtime', ?) WHERE business_day IS NULL",
+		fmt.Sprintf("-%d hours", businessDayStartHour()))
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS pickup_sequences (
+		business_day TEXT,
+		channel TEXT,
+		last_number INTEGER DEFAULT 0,
+		PRIMARY KEY(business_day, channel)
+	)`)
+
+	// Rush/VIP flags and the manual kitchen sequence shared by all KDS screens
+	addColumn(db, "orders", "flag TEXT")
+	addColumn(db, "orders", "queue_position INTEGER")
+
+	// Optional contact details captured at checkout (used by the KDS recall search)
+	addColumn(db, "orders", "customer_phone TEXT")
+
+	// 3. Create ORDER EVENTS Table (status history used by the display board)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS order_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER,
+		event TEXT,
+		detail TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(order_id) REFERENCES orders(id)
+	)`)
+
+	// Prep times learned from kitchen status changes, used for wait-time estimates
+	addColumn(db, "orders", "estimated_ready_at DATETIME")
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS prep_times (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER,
+		product_name TEXT,
+		category TEXT,
+		seconds INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+
+	// SLA breaches raised by the background monitor, kept for reporting
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS sla_breaches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER,
+		category TEXT,
+		threshold_minutes INTEGER,
+		breached_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		resolved_at DATETIME,
+		FOREIGN KEY(order_id) REFERENCES orders(id)
+	)`)
+
+	// How the order was paid (card, e-wallet, cash)
+	addColumn(db, "orders", "payment_method TEXT DEFAULT 'card'")
+
+	// Idempotency key of the cart submission that created the order (retries return the same order)
+	addColumn(db, "orders", "checkout_key TEXT")
+	db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_checkout_key ON orders(checkout_key)")
+
+	// Inventory: ingredients on hand, recipes (per product or per modifier) and every stock movement
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS ingredients (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE,
+		unit TEXT,
+		on_hand REAL DEFAULT 0
+	)`)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS recipe_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		product_id INTEGER,          -- NULL for modifier recipes
+		modifier_name TEXT DEFAULT '', -- e.g. 'Extra Cheese'
+		ingredient_id INTEGER,
+		quantity REAL,
+		FOREIGN KEY(product_id) REFERENCES products(id),
+		FOREIGN KEY(ingredient_id) REFERENCES ingredients(id)
+	)`)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS stock_movements (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ingredient_id INTEGER,
+		change REAL,
+		reason TEXT, -- sale, void, delivery, waste, count
+		order_id INTEGER,
+		note TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(ingredient_id) REFERENCES ingredients(id)
+	)`)
+	// Set when a product was sold out automatically because an ingredient ran out
+	addColumn(db, "products", "stock_sold_out INTEGER DEFAULT 0")
+
+	// Purchase cost per unit, used for recipe costing and margins
+	addColumn(db, "ingredients", "cost_per_unit REAL DEFAULT 0")
+
+	// Purchasing: suppliers, par levels and purchase orders received into stock
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS suppliers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE,
+		contact TEXT,
+		phone TEXT,
+		email TEXT
+	)`)
+	addColumn(db, "ingredients", "supplier_id INTEGER")
+	addColumn(db, "ingredients", "par_level REAL DEFAULT 0")
+
+	// Limited daily specials: 0 = unlimited; cap_sold_out is set when the cap sold it out
+	addColumn(db, "products", "daily_cap INTEGER DEFAULT 0")
+	addColumn(db, "products", "cap_sold_out INTEGER DEFAULT 0")
+
+	// Sold out by hand (KDS 86 panel or the admin In Stock box); in_stock is derived from
+	// this and the cap/stock flags. Products taken off before the flag existed keep their 86.
+	addColumn(db, "products", "manual_sold_out INTEGER DEFAULT 0")
+	db.Exec("UPDATE products SET manual_sold_out = 1 WHERE in_stock = 0 AND " + availableSQL)
+
+	// Availability schedules per product or per category
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS availability_windows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		product_id INTEGER,    -- NULL for category windows
+		category TEXT DEFAULT '',
+		days TEXT DEFAULT '',  -- comma-separated weekdays, 0 = Sunday; '' = every day
+		start_time TEXT DEFAULT '', -- HH:MM local; '' = all day
+		end_time TEXT DEFAULT '',
+		start_date TEXT DEFAULT '', -- YYYY-MM-DD; '' = open-ended
+		end_date TEXT DEFAULT '',
+		FOREIGN KEY(product_id) REFERENCES products(id)
+	)`)
+
+	// Allergen and dietary attributes on products and add-ons, and the customer's allergy note
+	addColumn(db, "products", "allergens TEXT DEFAULT ''") // comma-separated, see allergenList
+	addColumn(db, "products", "vegetarian INTEGER DEFAULT 0")
+	addColumn(db, "products", "spicy_level INTEGER DEFAULT 0")
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS modifier_attributes (
+		modifier_name TEXT PRIMARY KEY,
+		allergens TEXT DEFAULT '',
+		vegetarian INTEGER DEFAULT 1
+	)`)
+	addColumn(db, "orders", "allergy_note TEXT")
+
+	// Extra words a product should be found by in menu search (e.g. "soda, soft drink")
+	addColumn(db, "products", "search_tags TEXT DEFAULT ''")
+
+	// Menu translations: per-product name/description, and category and add-on labels
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS product_translations (
+		product_id INTEGER,
+		lang TEXT,
+		name TEXT,
+		description TEXT,
+		PRIMARY KEY (product_id, lang),
+		FOREIGN KEY(product_id) REFERENCES products(id)
+	)`)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS label_translations (
+		kind TEXT,  -- category, modifier
+		label TEXT, -- the English category or modifier name
+		lang TEXT,
+		text TEXT,
+		PRIMARY KEY (kind, label, lang)
+	)`)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS purchase_orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		supplier_id INTEGER,
+		status TEXT DEFAULT 'Draft', -- Draft, Sent, Received
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		sent_at DATETIME,
+		received_at DATETIME,
+		FOREIGN KEY(supplier_id) REFERENCES suppliers(id)
+	)`)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS purchase_order_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		po_id INTEGER,
+		ingredient_id INTEGER,
+		quantity REAL,
+		unit_cost REAL,      -- expected, from the ingredient's cost when ordered
+		received_qty REAL,
+		received_cost REAL,  -- actual unit cost on the invoice
+		FOREIGN KEY(po_id) REFERENCES purchase_orders(id),
+		FOREIGN KEY(ingredient_id) REFERENCES ingredients(id)
+	)`)
+
+	// Optional nutrition facts per product, and per add-on as a delta on top of the item
+	for _, table := range []string{"products", "modifier_attributes"} {
+		addColumn(db, table, "calories REAL") // NULL = not recorded
+		addColumn(db, table, "protein_g REAL")
+		addColumn(db, table, "fat_g REAL")
+		addColumn(db, table, "carbs_g REAL")
+		addColumn(db, table, "sodium_mg REAL")
+	}
+
+	// Business day closes: cash drawer float/count and the archived Z report (closed days are locked)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS business_days (
+		business_day TEXT PRIMARY KEY,
+		opening_float REAL DEFAULT 0,
+		counted_cash REAL,
+		expected_cash REAL,
+		closed_at DATETIME,
+		z_report TEXT -- JSON snapshot of the Z report at closing
+	)`)
+
+	// Structured order lines: product reference, remark and one row per chosen modifier
+	addColumn(db, "order_items", "product_id INTEGER")
+	addColumn(db, "order_items", "remarks TEXT")
+	db.Exec(`UPDATE order_items SET product_id = (SELECT id FROM products WHERE products.name = order_items.product_name)
+		WHERE product_id IS NULL`)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS order_item_options (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_item_id INTEGER,
+		group_name TEXT,
+		option_name TEXT,
+		price_delta REAL DEFAULT 0,
+		FOREIGN KEY(order_item_id) REFERENCES order_items(id)
+	)`)
+	backfillOrderItemOptions(db)
+
+	// Post-placement changes: voided lines, kitchen alert banners and refunds
+	addColumn(db, "order_items", "voided INTEGER DEFAULT 0")
+	addColumn(db, "order_items", "void_reason TEXT")
+	addColumn(db, "orders", "kitchen_alert TEXT")
+	// Extra owed after an amend made the order dearer; moves into total_amount once collected
+	addColumn(db, "orders", "surcharge_due REAL DEFAULT 0")
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS refunds (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER,
+		amount REAL,
+		reason TEXT,
+		status TEXT, -- pending, completed, failed
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(order_id) REFERENCES orders(id)
+	)`)
+
 	// 4. Create CATEGORIES Table
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS categories (
     name TEXT PRIMARY KEY
@@ -61,3 +299,11 @@ func initDB(db *sql.DB) {
 	// new_start_data()
 
 }
+
+// addColumn adds a column to an existing table, ignoring the error when it is already there
+func addColumn(db *sql.DB, table, columnDef string) {
+	_, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + columnDef)
+	if err != nil && !strings.Contains(err.Error(), "duplicate column") {
+		log.Printf("Error adding column to %s: %v", table, err)
+	}
+}
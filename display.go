@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// How long a ready order stays on the board if nobody marks it as picked up
+const readyDisplayTimeout = "-10 minutes"
+
+// 1. Render the Customer Display Board (wall TV at the pickup counter)
+func handleDisplayPage(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Now Serving</title>
+    <script src="https://unpkg.com/htmx.org@1.9.10"></script>
+    <style>
+        body {
+            background: #111;
+            color: #fff;
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            margin: 0;
+            height: 100vh;
+            overflow: hidden;
+        }
+
+        #board { display: grid; grid-template-columns: 1fr 1fr; height: 100vh; }
+
+        .column { padding: 2rem; box-sizing: border-box; overflow: hidden; }
+        .column h1 {
+            margin: 0 0 2rem 0;
+            font-size: 3rem;
+            text-transform: uppercase;
+            letter-spacing: 4px;
+            text-align: center;
+            padding-bottom: 1rem;
+        }
+        .preparing h1 { color: #f39c12; border-bottom: 6px solid #f39c12; }
+        .ready { background: #0b2e1a; }
+        .ready h1 { color: #2ecc71; border-bottom: 6px solid #2ecc71; }
+
+        .numbers { display: flex; flex-wrap: wrap; gap: 1.5rem; justify-content: center; }
+        .number {
+            font-size: 4rem;
+            font-weight: 800;
+            font-family: monospace;
+            min-width: 180px;
+            text-align: center;
+            padding: 0.5rem 1rem;
+            border-radius: 12px;
+            background: #222;
+        }
+        .ready .number { background: #145a32; font-size: 5rem; }
+
+        /* Flash numbers that just moved to Ready */
+        .number.just-ready { animation: flash 1s ease-in-out infinite; }
+        @keyframes flash {
+            0%, 100% { background: #145a32; transform: scale(1); }
+            50% { background: #2ecc71; color: #000; transform: scale(1.1); }
+        }
+
+        .empty { text-align: center; color: #555; font-size: 1.5rem; }
+
+        #sound-hint {
+            position: fixed; bottom: 10px; right: 10px;
+            font-size: 0.9rem; color: #555; cursor: pointer;
+        }
+    </style>
+</head>
+<body>
+    <div id="board" hx-get="/display/board" hx-trigger="load, every 3s"></div>
+    <div id="sound-hint" onclick="enableSound()">🔇 Click to enable chime</div>
+
+    <script>
+        let readySeen = null; // null until the first load, so existing numbers don't chime
+        let highlightUntil = {}; // order id -> timestamp until which it keeps flashing
+        let audioCtx = null;
+
+        function enableSound() {
+            audioCtx = new (window.AudioContext || window.webkitAudioContext)();
+            document.getElementById('sound-hint').innerText = "🔊";
+            chime();
+        }
+
+        // Two-tone "ding-dong" using WebAudio so no asset is needed
+        function chime() {
+            if (!audioCtx) return;
+            [880, 660].forEach((freq, i) => {
+                const osc = audioCtx.createOscillator();
+                const gain = audioCtx.createGain();
+                const start = audioCtx.currentTime + i * 0.4;
+                osc.frequency.value = freq;
+                gain.gain.setValueAtTime(0.3, start);
+                gain.gain.exponentialRampToValueAtTime(0.001, start + 0.8);
+                osc.connect(gain).connect(audioCtx.destination);
+                osc.start(start);
+                osc.stop(start + 0.8);
+            });
+        }
+
+        document.body.addEventListener('htmx:afterSwap', function(evt) {
+            if (evt.target.id !== 'board') return;
+            const current = new Set();
+            const now = Date.now();
+            let hasNew = false;
+            document.querySelectorAll('.ready .number').forEach(el => {
+                const id = el.getAttribute('data-id');
+                current.add(id);
+                if (readySeen !== null && !readySeen.has(id)) {
+                    hasNew = true;
+                    highlightUntil[id] = now + 30000;
+                }
+                // The board is re-rendered on every poll, so re-apply the highlight
+                if (highlightUntil[id] > now) el.classList.add('just-ready');
+            });
+            if (hasNew) chime();
+            readySeen = current;
+        });
+    </script>
+</body>
+</html>`)
+}
+
+// 2. Fetch the Preparing / Ready columns
+func handleDisplayBoard(w http.ResponseWriter, r *http.Request) {
+	preparing := getDisplayNumbers(`
+		SELECT id, COALESCE(pickup_number, id) FROM orders
+		WHERE status = 'Paid' AND created_at >= datetime('now', '-24 hours')
+		ORDER BY id ASC`)
+
+	// Ready = completed by the kitchen, not yet picked up, and still inside the display timeout
+	ready := getDisplayNumbers(`
+		SELECT o.id, COALESCE(o.pickup_number, o.id) FROM orders o
+		JOIN (SELECT order_id, MAX(created_at) AS ready_at FROM order_events
+		      WHERE event = 'status' AND detail = 'Completed' GROUP BY order_id) e ON e.order_id = o.id
+		WHERE o.status = 'Completed' AND e.ready_at >= datetime('now', ?)
+		ORDER BY e.ready_at DESC`, readyDisplayTimeout)
+
+	renderDisplayColumn(w, "preparing", "Preparing", preparing)
+	renderDisplayColumn(w, "ready", "Ready", ready)
+}
+
+// getDisplayNumbers returns orders with only ID and PickupNumber filled in
+func getDisplayNumbers(query string, args ...interface{}) []Order {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var o Order
+		rows.Scan(&o.ID, &o.PickupNumber)
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+func renderDisplayColumn(w http.ResponseWriter, cssClass, title string, orders []Order) {
+	fmt.Fprintf(w, `<div class="column %s"><h1>%s</h1><div class="numbers">`, cssClass, title)
+	if len(orders) == 0 {
+		fmt.Fprint(w, `<div class="empty">—</div>`)
+	}
+	for _, o := range orders {
+		fmt.Fprintf(w, `<div class="number" data-id="%d">%s</div>`, o.ID, o.Number())
+	}
+	fmt.Fprint(w, `</div></div>`)
+}
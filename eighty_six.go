@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A product is on sale only when nothing has sold it out: the kitchen (86'd by hand),
+// its daily cap or its ingredients. Each of those keeps its own flag and in_stock is
+// derived from all three here, so clearing one never overrides the others.
+const availableSQL = `COALESCE(manual_sold_out, 0) = 0 AND COALESCE(cap_sold_out, 0) = 0 AND COALESCE(stock_sold_out, 0) = 0`
+
+// applyAvailability recomputes in_stock from the sold-out flags
+func applyAvailability(ex execer) error {
+	_, err := ex.Exec(`UPDATE products SET in_stock = (` + availableSQL + `)`)
+	return err
+}
+
+// restoreExpired86 clears the 86 on products that were 86'd with auto-restore on an
+// earlier business day, so they come back automatically at the next open.
+// Daily specials that sold out on their cap are reset the same way.
+func restoreExpired86() {
+	today := businessDay(time.Now())
+	_, err := db.Exec(`UPDATE products SET manual_sold_out = 0, sold_out_day = NULL
+		WHERE sold_out_day IS NOT NULL AND sold_out_day < ?`, today)
+	if err != nil {
+		fmt.Println("Error restoring 86'd products:", err)
+	}
+	if err := refreshDailyCaps(db, today); err != nil {
+		fmt.Println("Error refreshing daily caps:", err)
+	}
+}
+
+// dayRolloverInterval is how often the background check looks for a new business day
+const dayRolloverInterval = time.Minute
+
+// startDayRollover runs restoreExpired86 at startup and again each time the business
+// day changes, instead of on every menu request
+func startDayRollover() {
+	go func() {
+		lastDay := ""
+		for {
+			if today := businessDay(time.Now()); today != lastDay {
+				restoreExpired86()
+				lastDay = today
+			}
+			time.Sleep(dayRolloverInterval)
+		}
+	}()
+}
+
+// handleKitchen86Panel renders the quick sold-out panel on the KDS
+func handleKitchen86Panel(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query(`SELECT id, category, name, in_stock, COALESCE(manual_sold_out, 0), COALESCE(stock_sold_out, 0), COALESCE(sold_out_day, '')
+		FROM products ORDER BY category, name`)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	fmt.Fprint(w, `
+	<div class="panel-overlay" onclick="if(event.target === this) this.remove()">
+		<div class="panel">
+			<div class="panel-header">
+				<h2>🚫 86 Items</h2>
+				<label style="font-size:0.9rem;"><input type="checkbox" id="auto-restore" checked> Auto-restore at next open</label>
+				<div class="icon-btn" onclick="this.closest('.panel-overlay').remove()">✕</div>
+			</div>
+			<div class="panel-body">`)
+
+	lastCat := ""
+	for rows.Next() {
+		var id int
+		var cat, name, soldOutDay string
+		var inStock, manual, noStock bool
+		rows.Scan(&id, &cat, &name, &inStock, &manual, &noStock, &soldOutDay)
+
+		if cat != lastCat {
+			fmt.Fprintf(w, `<h3 class="panel-cat">%s</h3>`, strings.ToUpper(cat))
+			lastCat = cat
+		}
+
+		cssClass, label := "", "Available"
+		switch {
+		case manual && soldOutDay != "":
+			cssClass, label = "is-86", "86'd · back tomorrow"
+		case manual:
+			cssClass, label = "is-86", "86'd"
+		case noStock:
+			cssClass, label = "is-86", "Out of ingredients"
+		case !inStock:
+			cssClass, label = "is-86", "Daily limit reached"
+		}
+		fmt.Fprintf(w, `
+				<button class="panel-item %s"
+					hx-post="/kitchen/86/toggle?id=%d"
+					hx-vals='js:{auto_restore: document.getElementById("auto-restore").checked ? "on" : ""}'
+					hx-target="closest .panel-overlay"
+					hx-swap="outerHTML">
+					<span>%s</span><small>%s</small>
+				</button>`, cssClass, id, name, label)
+	}
+
+	fmt.Fprint(w, `
+			</div>
+		</div>
+	</div>`)
+}
+
+// handleKitchen86Toggle flips a product between available and 86'd
+func handleKitchen86Toggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+
+	var manual bool
+	if err := db.QueryRow("SELECT COALESCE(manual_sold_out, 0) FROM products WHERE id = ?", id).Scan(&manual); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	if !manual {
+		// 86 it; remember the day when it should come back by itself
+		var soldOutDay interface{}
+		if r.FormValue("auto_restore") == "on" {
+			soldOutDay = businessDay(time.Now())
+		}
+		_, err = db.Exec("UPDATE products SET manual_sold_out = 1, sold_out_day = ? WHERE id = ?", soldOutDay, id)
+	} else {
+		// Stays sold out if its cap or its ingredients have run out
+		_, err = db.Exec("UPDATE products SET manual_sold_out = 0, sold_out_day = NULL WHERE id = ?", id)
+	}
+	if err == nil {
+		err = applyAvailability(db)
+	}
+	if err != nil {
+		fmt.Printf("Error updating stock: %v", err)
+	}
+
+	handleKitchen86Panel(w, r)
+}
+
+// handleMenuSoldOut lists sold-out product IDs; customer pages poll it to update cards live
+func handleMenuSoldOut(w http.ResponseWriter, r *http.Request) {
+	ids := []int{}
+	rows, err := db.Query("SELECT id FROM products WHERE in_stock = 0")
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			rows.Scan(&id)
+			ids = append(ids, id)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ids)
+}
+
+// soldOutCartItems flags, in cart order, the lines whose product is no longer in stock
+func soldOutCartItems() []bool {
+	soldOut := make([]bool, len(cart))
+	for i, item := range cart {
+		var inStock bool
+		err := db.QueryRow("SELECT in_stock FROM products WHERE id = ?", item.ProductID).Scan(&inStock)
+		if err != nil || !inStock {
+			soldOut[i] = true
+		}
+	}
+	return soldOut
+}
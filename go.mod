@@ -2,8 +2,9 @@ module apipizza
 
 go 1.25.4
 
+require github.com/mattn/go-sqlite3 v1.14.32
+
 require (
-	github.com/mattn/go-sqlite3 v1.14.32 // indirect
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646 // indirect
 	github.com/stripe/stripe-go/v84 v84.1.0 // indirect
 )
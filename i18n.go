@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Customers can read the menu in English, Malay or Chinese. The language comes from the
+// lang cookie (set by the switcher, /?lang=ms) or the browser's Accept-Language.
+// Orders keep the English names, so kitchen tickets are translated separately into the
+// kitchen language setting, whatever language the customer ordered in.
+
+const defaultLang = "en"
+
+var languages = []string{"en", "ms", "zh"}
+
+var languageNames = map[string]string{
+	"en": "English",
+	"ms": "Bahasa Melayu",
+	"zh": "中文",
+}
+
+func validLang(lang string) bool {
+	_, ok := languageNames[lang]
+	return ok
+}
+
+// requestLang is the customer's chosen language, else the best Accept-Language match
+func requestLang(r *http.Request) string {
+	if c, err := r.Cookie("lang"); err == nil && validLang(c.Value) {
+		return c.Value
+	}
+	return acceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// acceptLanguage picks the highest weighted supported language from an Accept-Language header,
+// matching on the primary tag so "zh-CN" and "ms-MY" count
+func acceptLanguage(header string) string {
+	best, bestQ := defaultLang, 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.SplitN(strings.TrimSpace(fields[0]), "-", 2)[0])
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(f), "q="); ok {
+				q, _ = strconv.ParseFloat(v, 64)
+			}
+		}
+		if validLang(tag) && q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
+
+// uiStrings holds the customer screens' fixed text, keyed by the English text
+var uiStrings = map[string]map[string]string{
+	"📍 DINE IN & SELF-PICKUP ONLY @ Cyber 12. For delivery, use": {"ms": "📍 MAKAN DI SINI & AMBIL SENDIRI SAHAJA @ Cyber 12. Untuk penghantaran, guna", "zh": "📍 仅限堂食及自取 @ Cyber 12。外送请使用"},
+	"Pizza":                          {"ms": "Piza", "zh": "披萨"},
+	"Pasta":                          {"ms": "Pasta", "zh": "意面"},
+	"Drinks":                         {"ms": "Minuman", "zh": "饮料"},
+	"Search for food, drinks...":     {"ms": "Cari makanan, minuman...", "zh": "搜索食物、饮料..."},
+	"Free from":                      {"ms": "Tanpa", "zh": "不含"},
+	"🥜 Nuts":                         {"ms": "🥜 Kacang", "zh": "🥜 坚果"},
+	"🦐 Seafood":                      {"ms": "🦐 Makanan Laut", "zh": "🦐 海鲜"},
+	"🥛 Dairy":                        {"ms": "🥛 Tenusu", "zh": "🥛 乳制品"},
+	"🌱 Vegetarian":                   {"ms": "🌱 Vegetarian", "zh": "🌱 素食"},
+	"🌶️ Any spice":                   {"ms": "🌶️ Sebarang tahap pedas", "zh": "🌶️ 任何辣度"},
+	"Not spicy":                      {"ms": "Tidak pedas", "zh": "不辣"},
+	"Up to mild":                     {"ms": "Sehingga kurang pedas", "zh": "最多微辣"},
+	"Up to medium":                   {"ms": "Sehingga sederhana pedas", "zh": "最多中辣"},
+	"Loading menu...":                {"ms": "Memuatkan menu...", "zh": "正在加载菜单..."},
+	"Your Order":                     {"ms": "Pesanan Anda", "zh": "您的订单"},
+	"Dine-in / Pickup":               {"ms": "Makan di sini / Ambil sendiri", "zh": "堂食 / 自取"},
+	"Your cart is empty":             {"ms": "Troli anda kosong", "zh": "购物车是空的"},
+	"Cart is empty":                  {"ms": "Troli kosong", "zh": "购物车是空的"},
+	"View Cart":                      {"ms": "Lihat Troli", "zh": "查看购物车"},
+	"Add to Order":                   {"ms": "Tambah ke Pesanan", "zh": "加入订单"},
+	"Sold Out":                       {"ms": "Habis Dijual", "zh": "已售完"},
+	"Add-ons":                        {"ms": "Tambahan", "zh": "加料"},
+	"Add remark (e.g. no onions)...": {"ms": "Tambah catatan (cth. tanpa bawang)...", "zh": "备注（例如：不要洋葱）..."},
+	"🔥 Only %d left today":           {"ms": "🔥 Tinggal %d sahaja hari ini", "zh": "🔥 今天仅剩 %d 份"},
+	"No matching items":              {"ms": "Tiada item yang sepadan", "zh": "没有找到相关餐点"},
+	`Try searching for something else like "Pizza" or "Coffee"`: {"ms": `Cuba cari sesuatu yang lain seperti "Piza" atau "Kopi"`, "zh": `试试搜索其他内容，例如"披萨"或"咖啡"`},
+	"Clear Search":        {"ms": "Kosongkan Carian", "zh": "清除搜索"},
+	"Your cart is empty.": {"ms": "Troli anda kosong.", "zh": "购物车是空的。"},
+	"Note:":               {"ms": "Catatan:", "zh": "备注："},
+	"Sold out":            {"ms": "Habis", "zh": "已售完"},
+	"remove":              {"ms": "buang", "zh": "移除"},
+	"Name (optional)":     {"ms": "Nama (pilihan)", "zh": "姓名（选填）"},
+	"Phone (optional)":    {"ms": "Telefon (pilihan)", "zh": "电话（选填）"},
+	"⚠️ Food allergy? Tell the kitchen (e.g. peanuts)": {"ms": "⚠️ Alahan makanan? Beritahu dapur (cth. kacang)", "zh": "⚠️ 食物过敏？请告诉厨房（例如：花生）"},
+	"💳 Card":            {"ms": "💳 Kad", "zh": "💳 银行卡"},
+	"📱 E-wallet":        {"ms": "📱 E-dompet", "zh": "📱 电子钱包"},
+	"💵 Cash at counter": {"ms": "💵 Tunai di kaunter", "zh": "💵 柜台付现"},
+	"Checkout & Pay":    {"ms": "Bayar Sekarang", "zh": "结账付款"},
+	"Some items just sold out. Remove them to check out.": {"ms": "Beberapa item baru sahaja habis. Buang item tersebut untuk membuat bayaran.", "zh": "部分餐点刚刚售完，请移除后再结账。"},
+	"Subtotal":                          {"ms": "Jumlah Kecil", "zh": "小计"},
+	"Tax (5%)":                          {"ms": "Cukai (5%)", "zh": "税 (5%)"},
+	"Total":                             {"ms": "Jumlah", "zh": "总计"},
+	"Estimated ready in %s (around %s)": {"ms": "Anggaran siap dalam %s (sekitar %s)", "zh": "预计 %s 后完成（约 %s）"},
+	"~%d mins":                          {"ms": "~%d minit", "zh": "约 %d 分钟"},
+	"Clear Order":                       {"ms": "Kosongkan Pesanan", "zh": "清空订单"},
+	"Nutrition":                         {"ms": "Nilai Pemakanan", "zh": "营养成分"},
+	"Nutrition info not available":      {"ms": "Maklumat pemakanan tiada", "zh": "暂无营养信息"},
+	"Excludes %d item(s) without nutrition info": {"ms": "Tidak termasuk %d item tanpa maklumat pemakanan", "zh": "不含 %d 件无营养信息的餐点"},
+	"Calories": {"ms": "Kalori", "zh": "热量"},
+	"Protein":  {"ms": "Protein", "zh": "蛋白质"},
+	"Fat":      {"ms": "Lemak", "zh": "脂肪"},
+	"Carbs":    {"ms": "Karbohidrat", "zh": "碳水化合物"},
+	"Sodium":   {"ms": "Natrium", "zh": "钠"},
+}
+
+// T translates a UI string, falling back to the English text
+func T(lang, s string) string {
+	if t, ok := uiStrings[s][lang]; ok {
+		return t
+	}
+	return s
+}
+
+// Built-in translations of the category and modifier labels; admins can override them
+var defaultLabels = map[string]map[string]string{
+	"category|pizza":         {"ms": "Piza", "zh": "披萨"},
+	"category|pasta":         {"ms": "Pasta", "zh": "意面"},
+	"category|drink":         {"ms": "Minuman", "zh": "饮料"},
+	"category|coffee":        {"ms": "Kopi", "zh": "咖啡"},
+	"category|dessert":       {"ms": "Pencuci Mulut", "zh": "甜点"},
+	"modifier|Extra Cheese":  {"ms": "Keju Tambahan", "zh": "加芝士"},
+	"modifier|Extra Topping": {"ms": "Topping Tambahan", "zh": "加配料"},
+	"modifier|Extra Pasta":   {"ms": "Pasta Tambahan", "zh": "加量意面"},
+	"modifier|Ice":           {"ms": "Ais", "zh": "冰"},
+	"modifier|Hot":           {"ms": "Panas", "zh": "热"},
+	"modifier|Regular":       {"ms": "Biasa", "zh": "正常甜"},
+	"modifier|Less Sweet":    {"ms": "Kurang Manis", "zh": "少甜"},
+	"modifier|Least Sweet":   {"ms": "Tanpa Gula", "zh": "无糖"},
+}
+
+type productText struct {
+	Name, Description string
+}
+
+// translator holds one language's product, category and modifier translations
+type translator struct {
+	Lang     string
+	products map[int]productText
+	labels   map[string]string // "kind|label" → text
+}
+
+// loadTranslator reads the translations for a language; English needs none
+func loadTranslator(lang string) translator {
+	tr := translator{Lang: lang, products: map[int]productText{}, labels: map[string]string{}}
+	if lang == defaultLang {
+		return tr
+	}
+	for key, texts := range defaultLabels {
+		if t, ok := texts[lang]; ok {
+			tr.labels[key] = t
+		}
+	}
+
+	rows, err := db.Query("SELECT product_id, COALESCE(name, ''), COALESCE(description, '') FROM product_translations WHERE lang = ?", lang)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return tr
+	}
+	for rows.Next() {
+		var id int
+		var t productText
+		rows.Scan(&id, &t.Name, &t.Description)
+		tr.products[id] = t
+	}
+	rows.Close()
+
+	rows, err = db.Query("SELECT kind, label, text FROM label_translations WHERE lang = ? AND text != ''", lang)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return tr
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, label, text string
+		rows.Scan(&kind, &label, &text)
+		tr.labels[kind+"|"+label] = text
+	}
+	return tr
+}
+
+// kitchenTranslator translates tickets into the kitchen language setting
+func kitchenTranslator() translator {
+	return loadTranslator(getSetting("kitchen_language", defaultLang))
+}
+
+func (tr translator) T(s string) string {
+	return T(tr.Lang, s)
+}
+
+func (tr translator) ProductName(id int, name string) string {
+	return orDefault(tr.products[id].Name, name)
+}
+
+func (tr translator) ProductDescription(id int, description string) string {
+	return orDefault(tr.products[id].Description, description)
+}
+
+func (tr translator) Category(category string) string {
+	return orDefault(tr.labels["category|"+category], category)
+}
+
+func (tr translator) Modifier(name string) string {
+	return orDefault(tr.labels["modifier|"+name], name)
+}
+
+func (tr translator) Modifiers(names []string) []string {
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = tr.Modifier(name)
+	}
+	return out
+}
+
+// translatedLanguages are the languages with per-product translations (not English)
+func translatedLanguages() []string {
+	return languages[1:]
+}
+
+// productTranslationInputs renders a product card's translated name and description fields
+func productTranslationInputs(productID int, trs map[string]translator) string {
+	var b strings.Builder
+	b.WriteString(`<details class="text-xs text-gray-600"><summary class="cursor-pointer select-none">🌐 Translations</summary><div class="mt-2 space-y-2">`)
+	for _, lang := range translatedLanguages() {
+		t := trs[lang].products[productID]
+		fmt.Fprintf(&b, `
+			<div>
+				<span class="font-semibold uppercase">%s</span>
+				<input type="text" name="name_%s" value="%s" placeholder="Name (%s)" class="w-full p-1 border border-dashed border-gray-300 rounded bg-transparent focus:bg-white focus:border-blue-500 focus:outline-none">
+				<textarea name="description_%s" rows="2" placeholder="Description (%s)" class="w-full mt-1 p-1 border border-dashed border-gray-300 rounded bg-transparent focus:bg-white focus:border-blue-500 focus:outline-none resize-none">%s</textarea>
+			</div>`, lang, lang, html.EscapeString(t.Name), languageNames[lang], lang, languageNames[lang], html.EscapeString(t.Description))
+	}
+	b.WriteString(`</div></details>`)
+	return b.String()
+}
+
+// saveProductTranslations stores the translated fields from an admin product card;
+// a language left blank falls back to English on the menu
+func saveProductTranslations(productID int, r *http.Request) error {
+	for _, lang := range translatedLanguages() {
+		name := strings.TrimSpace(r.FormValue("name_" + lang))
+		desc := strings.TrimSpace(r.FormValue("description_" + lang))
+		var err error
+		if name == "" && desc == "" {
+			_, err = db.Exec("DELETE FROM product_translations WHERE product_id = ? AND lang = ?", productID, lang)
+		} else {
+			_, err = db.Exec(`INSERT INTO product_translations (product_id, lang, name, description) VALUES (?, ?, ?, ?)
+				ON CONFLICT(product_id, lang) DO UPDATE SET name = excluded.name, description = excluded.description`,
+				productID, lang, name, desc)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// menuCategories lists every category name, for the category translation form
+func menuCategories() []string {
+	seen := map[string]bool{}
+	rows, err := db.Query("SELECT name FROM categories UNION SELECT DISTINCT category FROM products")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderLanguagesSection lets the admin pick the kitchen language and translate category and add-on labels
+func renderLanguagesSection(w http.ResponseWriter) {
+	trs := map[string]translator{}
+	for _, lang := range translatedLanguages() {
+		trs[lang] = loadTranslator(lang)
+	}
+	kitchenLang := getSetting("kitchen_language", defaultLang)
+
+	fmt.Fprint(w, `
+	<section>
+		<h2 class="text-2xl font-bold mb-6 text-gray-800 border-b pb-2">🌐 LANGUAGES</h2>
+		<form method="post" action="/admin/languages/kitchen" class="flex items-center gap-3 mb-6 text-sm">
+			<label class="font-semibold">Kitchen tickets in</label>
+			<select name="lang" class="border border-gray-300 rounded px-2 py-1 bg-white">`)
+	for _, lang := range languages {
+		selected := ""
+		if lang == kitchenLang {
+			selected = "selected"
+		}
+		fmt.Fprintf(w, `<option value="%s" %s>%s</option>`, lang, selected, languageNames[lang])
+	}
+	fmt.Fprint(w, `</select>
+			<button class="bg-gray-900 text-white rounded px-3 py-1">Save</button>
+		</form>
+		<div class="bg-white rounded-lg shadow-sm divide-y">`)
+
+	renderLabelRows := func(kind string, labels []string) {
+		for _, label := range labels {
+			fmt.Fprintf(w, `
+			<form method="post" action="/admin/languages/label" class="flex flex-wrap items-center gap-4 p-3 text-sm">
+				<input type="hidden" name="kind" value="%s">
+				<input type="hidden" name="label" value="%s">
+				<span class="font-semibold w-40"><span class="text-xs text-gray-400 uppercase">%s</span><br>%s</span>`,
+				kind, html.EscapeString(label), kind, html.EscapeString(label))
+			for _, lang := range translatedLanguages() {
+				text := trs[lang].labels[kind+"|"+label]
+				fmt.Fprintf(w, `<input type="text" name="text_%s" value="%s" placeholder="%s" class="border border-dashed border-gray-300 rounded px-2 py-1">`,
+					lang, html.EscapeString(text), languageNames[lang])
+			}
+			fmt.Fprint(w, `
+				<button class="ml-auto text-sm bg-gray-900 text-white rounded px-3 py-1">Save</button>
+			</form>`)
+		}
+	}
+	renderLabelRows("category", menuCategories())
+	renderLabelRows("modifier", knownModifiers())
+
+	fmt.Fprint(w, `
+		</div>
+	</section>`)
+}
+
+func handleAdminKitchenLanguage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lang := r.FormValue("lang")
+	if !validLang(lang) {
+		http.Error(w, "Unknown language", http.StatusBadRequest)
+		return
+	}
+	if err := setSetting("kitchen_language", lang); err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+func handleAdminLabelTranslation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	kind, label := r.FormValue("kind"), r.FormValue("label")
+	if (kind != "category" && kind != "modifier") || label == "" {
+		http.Error(w, "Invalid label", http.StatusBadRequest)
+		return
+	}
+	for _, lang := range translatedLanguages() {
+		_, err := db.Exec(`INSERT INTO label_translations (kind, label, lang, text) VALUES (?, ?, ?, ?)
+			ON CONFLICT(kind, label, lang) DO UPDATE SET text = excluded.text`,
+			kind, label, lang, strings.TrimSpace(r.FormValue("text_"+lang)))
+		if err != nil {
+			fmt.Println("DB Error:", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
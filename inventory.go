@@ -0,0 +1,466 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stock movement reasons; "sale" is recorded automatically at checkout and "void" when
+// a voided or cancelled line gives its ingredients back
+var stockReasons = []string{"delivery", "waste", "count"}
+
+type Ingredient struct {
+	ID     int
+	Name   string
+	Unit   string
+	OnHand float64
+	Cost   float64 // per unit
+}
+
+type RecipeLine struct {
+	ID           int
+	ProductID    int    // 0 for modifier recipes
+	ProductName  string // Product name or modifier name, for display
+	ModifierName string // "" for product recipes
+	IngredientID int
+	Ingredient   string
+	Unit         string
+	Quantity     float64
+}
+
+func getIngredients() []Ingredient {
+	rows, err := db.Query("SELECT id, name, COALESCE(unit, ''), on_hand, COALESCE(cost_per_unit, 0) FROM ingredients ORDER BY name")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var out []Ingredient
+	for rows.Next() {
+		var i Ingredient
+		rows.Scan(&i.ID, &i.Name, &i.Unit, &i.OnHand, &i.Cost)
+		out = append(out, i)
+	}
+	return out
+}
+
+func getRecipeLines() []RecipeLine {
+	rows, err := db.Query(`
+		SELECT ri.id, COALESCE(ri.product_id, 0), COALESCE(p.name, ri.modifier_name), COALESCE(ri.modifier_name, ''),
+		       i.id, i.name, COALESCE(i.unit, ''), ri.quantity
+		FROM recipe_items ri
+		JOIN ingredients i ON i.id = ri.ingredient_id
+		LEFT JOIN products p ON p.id = ri.product_id
+		ORDER BY COALESCE(ri.modifier_name, '') != '', COALESCE(p.name, ri.modifier_name), i.name`)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var out []RecipeLine
+	for rows.Next() {
+		var l RecipeLine
+		rows.Scan(&l.ID, &l.ProductID, &l.ProductName, &l.ModifierName, &l.IngredientID, &l.Ingredient, &l.Unit, &l.Quantity)
+		out = append(out, l)
+	}
+	return out
+}
+
+// knownModifiers lists modifier names a recipe or attributes can be attached to: the paid
+// add-ons and options that have been ordered
+func knownModifiers() []string {
+	seen := map[string]bool{}
+	for name := range addonPrices {
+		seen[name] = true
+	}
+	rows, err := db.Query("SELECT DISTINCT option_name FROM order_item_options")
+	if err == nil {
+		for rows.Next() {
+			var name string
+			rows.Scan(&name)
+			seen[name] = true
+		}
+		rows.Close()
+	}
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cartUsage adds up the ingredients a cart consumes: each product's recipe plus the recipe of every chosen modifier
+func cartUsage(tx *sql.Tx, items []CartItem) (map[int]float64, error) {
+	usage := map[int]float64{}
+	add := func(query string, arg interface{}) error {
+		rows, err := tx.Query(query, arg)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var ingredientID int
+			var qty float64
+			rows.Scan(&ingredientID, &qty)
+			usage[ingredientID] += qty
+		}
+		return rows.Err()
+	}
+
+	for _, item := range items {
+		if err := add("SELECT ingredient_id, quantity FROM recipe_items WHERE product_id = ?", item.ProductID); err != nil {
+			return nil, err
+		}
+		for _, m := range item.Options {
+			if err := add("SELECT ingredient_id, quantity FROM recipe_items WHERE product_id IS NULL AND modifier_name = ?", m.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return usage, nil
+}
+
+// depleteStock takes an order's ingredients off the shelf as part of the checkout transaction
+func depleteStock(tx *sql.Tx, orderID int64, items []CartItem) error {
+	usage, err := cartUsage(tx, items)
+	if err != nil {
+		return err
+	}
+	for ingredientID, qty := range usage {
+		if _, err := tx.Exec("UPDATE ingredients SET on_hand = on_hand - ? WHERE id = ?", qty, ingredientID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO stock_movements (ingredient_id, change, reason, order_id) VALUES (?, ?, 'sale', ?)", ingredientID, -qty, orderID); err != nil {
+			return err
+		}
+	}
+	return refreshStockAvailability(tx)
+}
+
+// shortOfStock is checked inside the checkout transaction after the order's items are written,
+// like overDailyCap, so two carts can't both take the last of an ingredient.
+// Returns the cart's products that can no longer be made from what's on hand.
+func shortOfStock(tx *sql.Tx, items []CartItem) ([]string, error) {
+	usage, err := cartUsage(tx, items)
+	if err != nil {
+		return nil, err
+	}
+	short := map[int]bool{}
+	for ingredientID, qty := range usage {
+		var onHand float64
+		if err := tx.QueryRow("SELECT on_hand FROM ingredients WHERE id = ?", ingredientID).Scan(&onHand); err != nil {
+			return nil, err
+		}
+		if onHand < qty-1e-9 {
+			short[ingredientID] = true
+		}
+	}
+	if len(short) == 0 {
+		return nil, nil
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		lineUsage, err := cartUsage(tx, []CartItem{item})
+		if err != nil {
+			return nil, err
+		}
+		for ingredientID := range lineUsage {
+			if short[ingredientID] && !seen[item.Name] {
+				seen[item.Name] = true
+				names = append(names, item.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// returnStock puts the ingredients of voided or cancelled lines back on the shelf.
+// Orders placed before stock was tracked took nothing, so they give nothing back.
+func returnStock(tx *sql.Tx, orderID int, items []CartItem, note string) error {
+	var sales int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM stock_movements WHERE order_id = ? AND reason = 'sale'", orderID).Scan(&sales); err != nil || sales == 0 {
+		return err
+	}
+	usage, err := cartUsage(tx, items)
+	if err != nil {
+		return err
+	}
+	for ingredientID, qty := range usage {
+		if _, err := tx.Exec("UPDATE ingredients SET on_hand = on_hand + ? WHERE id = ?", qty, ingredientID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO stock_movements (ingredient_id, change, reason, order_id, note) VALUES (?, ?, 'void', ?, ?)", ingredientID, qty, orderID, note); err != nil {
+			return err
+		}
+	}
+	return refreshStockAvailability(tx)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// refreshStockAvailability marks products that can no longer be made from what's on hand,
+// and clears the mark once stock has been replenished
+func refreshStockAvailability(ex execer) error {
+	if _, err := ex.Exec(`UPDATE products SET stock_sold_out = EXISTS (SELECT 1 FROM recipe_items ri JOIN ingredients i ON i.id = ri.ingredient_id
+		WHERE ri.product_id = products.id AND i.on_hand < ri.quantity)`); err != nil {
+		return err
+	}
+	return applyAvailability(ex)
+}
+
+// handleAdminInventory is the stock-take screen: ingredients, adjustments, recipes and recent movements
+func handleAdminInventory(w http.ResponseWriter, r *http.Request) {
+	ingredients := getIngredients()
+
+	adminPageStart(w, "Inventory")
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		fmt.Fprintf(w, `<div class="bg-green-50 border border-green-200 text-green-800 rounded p-3">%s</div>`, html.EscapeString(msg))
+	}
+
+	reasonOptions := ""
+	for _, reason := range stockReasons {
+		reasonOptions += fmt.Sprintf(`<option value="%s">%s</option>`, reason, reason)
+	}
+
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">📦 Stock on hand</h2>
+        <table class="w-full text-sm">
+            <thead class="text-left text-gray-500"><tr><th>Ingredient</th><th>On hand</th><th>Cost / unit</th><th>Adjust (delivery / waste = amount, count = counted total)</th></tr></thead><tbody>`)
+	for _, i := range ingredients {
+		cls := ""
+		if i.OnHand <= 0 {
+			cls = "text-red-600 font-bold"
+		}
+		fmt.Fprintf(w, `
+            <tr class="border-t">
+                <td class="py-2 font-semibold">%s</td>
+                <td class="%s">%g %s</td>
+                <td><form method="post" action="/admin/inventory/cost" class="flex gap-1 items-center">
+                    <input type="hidden" name="id" value="%d">
+                    RM <input type="number" name="cost" step="any" min="0" value="%g" class="border rounded px-2 py-1 w-24">
+                    <button class="text-blue-600 hover:underline">Set</button>
+                </form></td>
+                <td><form method="post" action="/admin/inventory/adjust" class="flex gap-2">
+                    <input type="hidden" name="id" value="%d">
+                    <select name="reason" class="border rounded px-2 py-1">%s</select>
+                    <input type="number" name="quantity" step="any" required class="border rounded px-2 py-1 w-28">
+                    <input type="text" name="note" placeholder="Note" class="border rounded px-2 py-1">
+                    <button class="bg-gray-900 text-white rounded px-3 py-1 font-semibold">Save</button>
+                </form></td>
+            </tr>`, html.EscapeString(i.Name), cls, i.OnHand, html.EscapeString(i.Unit), i.ID, i.Cost, i.ID, reasonOptions)
+	}
+	if len(ingredients) == 0 {
+		fmt.Fprint(w, `<tr><td colspan="4" class="py-6 text-center text-gray-400">No ingredients yet</td></tr>`)
+	}
+	fmt.Fprint(w, `</tbody></table>
+        <form method="post" action="/admin/inventory/ingredient" class="flex gap-2 mt-4 text-sm">
+            <input type="text" name="name" placeholder="New ingredient (e.g. Mozzarella)" required class="border rounded px-2 py-1">
+            <input type="text" name="unit" placeholder="Unit (g, ml, pcs)" required class="border rounded px-2 py-1 w-32">
+            <input type="number" name="on_hand" step="any" placeholder="On hand" class="border rounded px-2 py-1 w-28">
+            <input type="number" name="cost" step="any" min="0" placeholder="Cost / unit (RM)" class="border rounded px-2 py-1 w-36">
+            <button class="bg-orange-600 text-white rounded px-3 py-1 font-semibold">+ Add ingredient</button>
+        </form>
+    </section>`)
+
+	// Recipes
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">🧑‍🍳 Recipes</h2>
+        <table class="w-full text-sm"><tbody>`)
+	for _, l := range getRecipeLines() {
+		label := html.EscapeString(l.ProductName)
+		if l.ModifierName != "" {
+			label = "+ " + label + ` <small class="text-gray-400">(modifier)</small>`
+		}
+		fmt.Fprintf(w, `
+            <tr class="border-t"><td class="py-1">%s</td><td>%s</td><td>%g %s</td>
+                <td class="text-right"><form method="post" action="/admin/inventory/recipe/delete"><input type="hidden" name="id" value="%d"><button class="text-red-600 hover:underline">Remove</button></form></td></tr>`,
+			label, html.EscapeString(l.Ingredient), l.Quantity, html.EscapeString(l.Unit), l.ID)
+	}
+	fmt.Fprint(w, `</tbody></table>
+        <form method="post" action="/admin/inventory/recipe" class="flex gap-2 mt-4 text-sm">
+            <select name="target" required class="border rounded px-2 py-1"><optgroup label="Products">`)
+	rows, err := db.Query("SELECT id, name FROM products ORDER BY category, name")
+	if err == nil {
+		for rows.Next() {
+			var id int
+			var name string
+			rows.Scan(&id, &name)
+			fmt.Fprintf(w, `<option value="product:%d">%s</option>`, id, html.EscapeString(name))
+		}
+		rows.Close()
+	}
+	fmt.Fprint(w, `</optgroup><optgroup label="Modifiers">`)
+	for _, name := range knownModifiers() {
+		fmt.Fprintf(w, `<option value="modifier:%s">%s</option>`, html.EscapeString(name), html.EscapeString(name))
+	}
+	fmt.Fprint(w, `</optgroup></select><select name="ingredient_id" required class="border rounded px-2 py-1">`)
+	for _, i := range ingredients {
+		fmt.Fprintf(w, `<option value="%d">%s (%s)</option>`, i.ID, html.EscapeString(i.Name), html.EscapeString(i.Unit))
+	}
+	fmt.Fprint(w, `</select>
+            <input type="number" name="quantity" step="any" min="0" placeholder="Qty per item" required class="border rounded px-2 py-1 w-32">
+            <button class="bg-orange-600 text-white rounded px-3 py-1 font-semibold">+ Add to recipe</button>
+        </form>
+    </section>`)
+
+	// Recent movements
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">🕑 Recent movements</h2>
+        <table class="w-full text-sm"><tbody>`)
+	rows, err = db.Query(`SELECT m.created_at, i.name, m.change, COALESCE(i.unit, ''), m.reason, COALESCE(m.order_id, 0), COALESCE(m.note, '')
+		FROM stock_movements m JOIN ingredients i ON i.id = m.ingredient_id ORDER BY m.id DESC LIMIT 50`)
+	if err == nil {
+		for rows.Next() {
+			var at, name, unit, reason, note string
+			var change float64
+			var orderID int
+			rows.Scan(&at, &name, &change, &unit, &reason, &orderID, &note)
+			if orderID > 0 {
+				note = fmt.Sprintf("order ref %d", orderID)
+			}
+			fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 text-gray-500">%s</td><td>%s</td><td>%+g %s</td><td>%s</td><td class="text-gray-500">%s</td></tr>`,
+				parseDBTime(at).Local().Format("Jan 2, 3:04 pm"), html.EscapeString(name), change, html.EscapeString(unit), reason, html.EscapeString(note))
+		}
+		rows.Close()
+	}
+	fmt.Fprint(w, `</tbody></table></section>`)
+	adminPageEnd(w)
+}
+
+func redirectToInventory(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, "/admin/inventory?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// handleAdminIngredientCreate adds an ingredient with its opening quantity
+func handleAdminIngredientCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	onHand, _ := strconv.ParseFloat(r.FormValue("on_hand"), 64)
+	cost, _ := strconv.ParseFloat(r.FormValue("cost"), 64)
+	res, err := db.Exec("INSERT INTO ingredients (name, unit, on_hand, cost_per_unit) VALUES (?, ?, ?, ?)", name, strings.TrimSpace(r.FormValue("unit")), onHand, cost)
+	if err != nil {
+		http.Error(w, "Could not add ingredient: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if onHand != 0 {
+		id, _ := res.LastInsertId()
+		db.Exec("INSERT INTO stock_movements (ingredient_id, change, reason, note) VALUES (?, ?, 'count', 'opening stock')", id, onHand)
+	}
+	redirectToInventory(w, r, "Added "+name)
+}
+
+// handleAdminStockAdjust records a delivery, waste or stock count
+func handleAdminStockAdjust(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	qty, err := strconv.ParseFloat(r.FormValue("quantity"), 64)
+	if err != nil {
+		http.Error(w, "Enter a quantity", http.StatusBadRequest)
+		return
+	}
+
+	var onHand float64
+	if err := db.QueryRow("SELECT on_hand FROM ingredients WHERE id = ?", id).Scan(&onHand); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var change float64
+	switch reason := r.FormValue("reason"); reason {
+	case "delivery":
+		change = qty
+	case "waste":
+		change = -qty
+	case "count":
+		change = qty - onHand
+	default:
+		http.Error(w, "Unknown reason", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("UPDATE ingredients SET on_hand = on_hand + ? WHERE id = ?", change, id)
+	if err == nil {
+		_, err = tx.Exec("INSERT INTO stock_movements (ingredient_id, change, reason, note) VALUES (?, ?, ?, ?)", id, change, r.FormValue("reason"), r.FormValue("note"))
+	}
+	if err == nil {
+		err = refreshStockAvailability(tx)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	redirectToInventory(w, r, "Stock updated")
+}
+
+// handleAdminRecipeAdd links a product or modifier to an ingredient it uses
+func handleAdminRecipeAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ingredientID, _ := strconv.Atoi(r.FormValue("ingredient_id"))
+	qty, _ := strconv.ParseFloat(r.FormValue("quantity"), 64)
+
+	var productID interface{}
+	modifier := ""
+	kind, value, _ := strings.Cut(r.FormValue("target"), ":")
+	switch kind {
+	case "product":
+		productID, _ = strconv.Atoi(value)
+	case "modifier":
+		modifier = value
+	default:
+		http.Error(w, "Pick a product or modifier", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := db.Exec("INSERT INTO recipe_items (product_id, modifier_name, ingredient_id, quantity) VALUES (?, ?, ?, ?)",
+		productID, modifier, ingredientID, qty); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	refreshStockAvailability(db)
+	redirectToInventory(w, r, "Recipe updated")
+}
+
+func handleAdminRecipeDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	db.Exec("DELETE FROM recipe_items WHERE id = ?", r.FormValue("id"))
+	refreshStockAvailability(db)
+	redirectToInventory(w, r, "Recipe updated")
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// KDS keyboard / bump-bar actions, in the order they're shown in the editor
+var kdsActions = []struct {
+	Key   string
+	Label string
+}{
+	{"select_prev", "Select previous ticket"},
+	{"select_next", "Select next ticket"},
+	{"bump", "Bump (complete) selected ticket"},
+	{"recall", "Recall last bumped ticket"},
+	{"priority", "Toggle RUSH on selected ticket"},
+	{"vip", "Toggle VIP on selected ticket"},
+	{"move_earlier", "Move selected ticket earlier"},
+	{"move_later", "Move selected ticket later"},
+	{"scroll_up", "Scroll up"},
+	{"scroll_down", "Scroll down"},
+}
+
+// Default key map; values are KeyboardEvent.key names. Digits 1-9 always select the Nth ticket.
+var defaultKeyMap = map[string]string{
+	"select_prev":  "ArrowLeft",
+	"select_next":  "ArrowRight",
+	"bump":         "Enter",
+	"recall":       "r",
+	"priority":     "p",
+	"vip":          "v",
+	"move_earlier": "[",
+	"move_later":   "]",
+	"scroll_up":    "ArrowUp",
+	"scroll_down":  "ArrowDown",
+}
+
+// loadKeyMap returns the key map saved for a screen, falling back to the defaults
+func loadKeyMap(screen string) map[string]string {
+	keyMap := map[string]string{}
+	for k, v := range defaultKeyMap {
+		keyMap[k] = v
+	}
+	saved := map[string]string{}
+	if err := json.Unmarshal([]byte(getSetting("kds_keymap:"+screen, "{}")), &saved); err == nil {
+		for k, v := range saved {
+			if v != "" {
+				keyMap[k] = v
+			}
+		}
+	}
+	return keyMap
+}
+
+// handleKitchenKeyMap serves a screen's key map as JSON for the KDS script
+func handleKitchenKeyMap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loadKeyMap(r.URL.Query().Get("screen")))
+}
+
+// handleKitchenKeyMapEdit shows and saves the key map editor for one screen
+func handleKitchenKeyMapEdit(w http.ResponseWriter, r *http.Request) {
+	screen := strings.TrimSpace(r.FormValue("screen"))
+
+	saved := ""
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		keyMap := map[string]string{}
+		for _, a := range kdsActions {
+			keyMap[a.Key] = r.FormValue(a.Key)
+		}
+		data, _ := json.Marshal(keyMap)
+		if err := setSetting("kds_keymap:"+screen, string(data)); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		saved = `<p style="color:#2ecc71;">✔ Saved. Reload the KDS screen to apply.</p>`
+	}
+
+	keyMap := loadKeyMap(screen)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>KDS Key Map</title>
+    <style>
+        body { background: #222; color: #fff; font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; padding: 2rem; }
+        label { display: flex; justify-content: space-between; align-items: center; max-width: 500px; margin-bottom: 10px; }
+        input { background: #000; color: #fff; border: 2px solid #444; border-radius: 6px; padding: 8px; font-family: monospace; width: 160px; }
+        button { background: #27ae60; color: #fff; border: none; padding: 12px 24px; font-weight: bold; border-radius: 6px; cursor: pointer; }
+        a { color: #3498db; }
+    </style>
+</head>
+<body>
+    <h1>⌨️ Key Map · %s</h1>
+    <p>Click a field and press the key (or bump-bar button) to assign it. Digits 1-9 always select the Nth ticket.</p>
+    %s
+    <form method="post">
+        <input type="hidden" name="screen" value="%s">`, screenLabel(screen), saved, html.EscapeString(screen))
+
+	for _, a := range kdsActions {
+		fmt.Fprintf(w, `
+        <label>%s <input name="%s" value="%s" readonly onkeydown="event.preventDefault(); this.value = event.key;"></label>`,
+			a.Label, a.Key, html.EscapeString(keyMap[a.Key]))
+	}
+
+	fmt.Fprint(w, `
+        <button type="submit">Save</button>
+        <a href="/kitchen" style="margin-left: 20px;">Back to KDS</a>
+    </form>
+</body>
+</html>`)
+}
+
+func screenLabel(screen string) string {
+	if screen == "" {
+		return "Default screen"
+	}
+	return html.EscapeString(screen)
+}
+
+// handleKitchenAction runs a keyboard/bump-bar action and records it in the order history
+func handleKitchenAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	action := r.URL.Query().Get("action")
+	id := r.URL.Query().Get("id")
+	source := "keyboard"
+	if screen := r.FormValue("screen"); screen != "" {
+		source += " @ " + screen
+	}
+
+	// Recall finds its own order below; every other action targets the given one
+	if action != "recall" && orderLocked(id) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+
+	switch action {
+	case "bump":
+		if err := updateOrderStatus(id, StatusCompleted); err != nil {
+			fmt.Printf("Error bumping: %v", err)
+		}
+		recordOrderEvent(id, "bump", source)
+	case "recall":
+		// The most recently bumped order that hasn't been picked up yet
+		err := db.QueryRow(`SELECT e.order_id FROM order_events e JOIN orders o ON o.id = e.order_id
+			WHERE e.event = 'status' AND e.detail = 'Completed' AND o.status = 'Completed'
+			ORDER BY e.id DESC LIMIT 1`).Scan(&id)
+		if err == nil && !orderLocked(id) {
+			updateOrderStatus(id, StatusPaid)
+			recordOrderEvent(id, "recall", source)
+		}
+	case "priority":
+		orderID, _ := strconv.Atoi(id)
+		toggleFlag(orderID, FlagRush)
+		recordOrderEvent(id, "flag", FlagRush+" ("+source+")")
+	case "vip":
+		orderID, _ := strconv.Atoi(id)
+		toggleFlag(orderID, FlagVIP)
+		recordOrderEvent(id, "flag", FlagVIP+" ("+source+")")
+	case "move_earlier", "move_later":
+		orderID, _ := strconv.Atoi(id)
+		delta := -1
+		if action == "move_later" {
+			delta = 1
+		}
+		shiftOrder(orderID, delta)
+		recordOrderEvent(id, "move", action+" ("+source+")")
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+
+	handleGetKitchenOrders(w, r)
+}
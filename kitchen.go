@@ -2,23 +2,59 @@ package main
 
 import (
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
+// Order statuses as they move through the kitchen and the pickup counter
+const (
+	StatusPaid      = "Paid"      // Placed, waiting/being prepared
+	StatusCompleted = "Completed" // Kitchen finished, ready for pickup
+	StatusPickedUp  = "Picked Up" // Handed over to the customer
+	StatusCancelled = "Cancelled" // Voided by staff (refunded)
+)
+
 type Order struct {
-	ID        int
-	Customer  string
-	Total     float64
-	Status    string
-	CreatedAt string
-	Items     []OrderItem
+	ID           int
+	Customer     string
+	Phone        string
+	Total        float64
+	Status       string
+	CreatedAt    string
+	PickupNumber string
+	EstimatedAt  string // Estimated ready time promised at checkout
+	Flag         string // "", "rush" or "vip"
+	Late         bool   // Has an open SLA breach
+	KitchenAlert string // "MODIFIED" / "VOID" until the kitchen acknowledges
+	Channel      string
+	Payment      string // Payment method, see paymentMethods
+	Allergy      string // Customer's allergy note, highlighted on tickets
+	Items        []OrderItem
 }
 
+// Number is what customers and staff call the order by; older orders fall back to the ID
+func (o Order) Number() string {
+	if o.PickupNumber != "" {
+		return o.PickupNumber
+	}
+	return fmt.Sprintf("%d", o.ID)
+}
+
+// Columns scanned by getOrdersByQuery, in order
+const orderColumns = "id, customer_name, total_amount, status, created_at, COALESCE(pickup_number, ''), COALESCE(estimated_ready_at, ''), COALESCE(flag, ''), COALESCE(customer_phone, ''), COALESCE(kitchen_alert, ''), COALESCE(channel, 'web'), COALESCE(payment_method, 'card'), COALESCE(allergy_note, '')"
+
 type OrderItem struct {
-	Name    string
-	Options string
-	Price   float64
+	ID        int
+	ProductID int
+	Name      string
+	Options   string     // Legacy combined text, see formatOptions
+	Modifiers []Modifier // Empty for orders placed before modifiers were stored
+	Remarks   string
+	Price     float64
+	Voided    bool
 }
 
 // 1. Render the Kitchen Page Skeleton (Updated CSS, JS, and Header)
@@ -130,6 +166,34 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
             overflow: hidden; /* Contains corners */
         }
         .ticket.completed-ticket { opacity: 0.6; filter: grayscale(0.6); }
+        .ticket.rush-ticket { border: 3px solid #e74c3c; }
+        .ticket.rush-ticket .ticket-header { background: #922b21; }
+        .ticket.vip-ticket { border: 3px solid #f1c40f; }
+        .ticket.vip-ticket .ticket-header { background: #7d6608; }
+        .flag-badge { font-size: 1rem; background: #000; padding: 2px 8px; border-radius: 4px; }
+        .ticket.drag-over { outline: 4px dashed #f39c12; }
+
+        .flag-btns { display: flex; }
+        .btn-void { background-color: #c0392b; color: white; }
+        .btn-void:hover { background-color: #a93226; }
+        .void-item { color: #e74c3c !important; }
+
+        /* MODIFIED / VOID banners */
+        .alert-banner { padding: 10px; font-size: 1.4rem; font-weight: 900; text-align: center; cursor: pointer; animation: pulse 1s infinite; }
+        .alert-modified { background: #d35400; }
+        .alert-void { background: #c0392b; }
+
+        /* Customer allergy note */
+        .ticket.allergy-ticket { border: 3px solid #ff00aa; }
+        .allergy-banner { padding: 8px 10px; font-size: 1.3rem; font-weight: 900; background: #ff00aa; color: #fff; text-align: center; }
+
+        /* EDIT PANEL */
+        .edit-item { border: 2px solid #444; border-radius: 6px; padding: 10px; margin-bottom: 10px; display: flex; flex-direction: column; gap: 8px; }
+        .edit-item.voided { color: #777; }
+        .edit-item input, .edit-item select { background: #000; color: #fff; border: 2px solid #444; border-radius: 6px; padding: 8px; font-size: 1rem; }
+        .btn-flag { background: #2c2c2c; color: #aaa; min-height: 44px; font-size: 1rem; border-top: 1px solid #555; }
+        .btn-flag:hover { background: #444; color: #fff; }
+        .ticket.selected { outline: 4px solid #3498db; outline-offset: 3px; }
 
         .ticket-header { 
             background: #444;
@@ -169,6 +233,16 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
             color: #fff;
         }
         
+        .ticket-remark {
+            display: block;
+            margin-top: 4px;
+            padding: 2px 6px;
+            background: #f1c40f;
+            color: #000;
+            font-weight: bold;
+            border-radius: 4px;
+        }
+
         .ticket-opt { 
             display: block; 
             font-size: 0.95rem; 
@@ -200,6 +274,63 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
         .btn-restore { background-color: #555; color: #ccc; }
         .btn-restore:hover { background-color: #777; color: white; }
 
+        .btn-pickup { background-color: #2980b9; color: white; }
+        .btn-pickup:hover { background-color: #2471a3; }
+
+        /* SIDE PANELS (86 items, ...) */
+        .panel-overlay {
+            position: fixed; inset: 0; z-index: 200;
+            background: rgba(0,0,0,0.6);
+            display: flex; justify-content: flex-end;
+        }
+        .panel {
+            width: min(520px, 100vw); height: 100vh;
+            background: #1a1a1a; border-left: 2px solid #444;
+            display: flex; flex-direction: column;
+        }
+        .panel-header {
+            display: flex; justify-content: space-between; align-items: center; gap: 10px;
+            padding: 10px 20px; border-bottom: 2px solid #444;
+        }
+        .panel-header h2 { margin: 0; }
+        .panel-body { overflow-y: auto; padding: 10px 20px 40px 20px; }
+        .panel-cat { color: #777; letter-spacing: 2px; margin: 20px 0 8px 0; font-size: 0.9rem; }
+        .panel-item {
+            width: 100%; display: flex; justify-content: space-between; align-items: center;
+            padding: 14px; margin-bottom: 6px; font-size: 1.1rem; font-weight: bold;
+            background: #2c2c2c; color: #fff; border: 2px solid #27ae60; border-radius: 6px; cursor: pointer;
+        }
+        .panel-item small { color: #27ae60; font-weight: normal; }
+        .panel-item.is-86 { border-color: #e74c3c; background: #3a1d1d; text-decoration: line-through; }
+        .panel-item.is-86 small { color: #e74c3c; text-decoration: none; }
+
+        /* SLA ALERTS */
+        .sla-banner {
+            background: #c0392b; color: #fff; font-weight: bold; font-size: 1.2rem;
+            padding: 10px 1rem; animation: pulse 1.5s infinite;
+        }
+        @keyframes pulse { 50% { background: #e74c3c; } }
+        .ticket.late-ticket { box-shadow: 0 0 0 3px #e74c3c, 0 4px 8px rgba(0,0,0,0.4); }
+
+        /* ALL-DAY COUNTS */
+        .all-day {
+            display: flex; flex-wrap: wrap; gap: 8px; align-items: center;
+            padding: 10px 1rem; background: #111; border-bottom: 2px solid #444;
+        }
+        .all-day-title { font-weight: bold; color: #f39c12; margin-right: 10px; text-transform: uppercase; }
+        .all-day-empty { color: #555; }
+        .all-day-item { background: #333; border: 1px solid #555; border-radius: 6px; padding: 6px 10px; font-size: 1.1rem; }
+        .all-day-item b { color: #f39c12; }
+        .all-day-item small { color: #3498db; margin-left: 4px; }
+
+        /* RECALL PANEL */
+        .recall-form { padding: 10px 20px; display: flex; flex-direction: column; gap: 8px; border-bottom: 1px solid #444; }
+        .recall-form input { background: #000; color: #fff; border: 2px solid #444; border-radius: 6px; padding: 10px; font-size: 1.1rem; flex: 1; }
+        .recall-row { border-color: #555; text-align: left; }
+        .recall-row small { color: #aaa; text-align: right; }
+        .history { width: 100%; border-collapse: collapse; font-size: 0.9rem; color: #bbb; }
+        .history td { border-bottom: 1px solid #333; padding: 4px; }
+
         /* Animation */
         @keyframes fadeIn { from { opacity: 0; transform: translateY(10px); } to { opacity: 1; transform: translateY(0); } }
         
@@ -223,8 +354,24 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
 
         <div style="font-size: 1.5rem; font-weight:bold; color: #444;">KDS</div>
 
+        <!-- ONLINE ORDERING PAUSE -->
+        <div id="pause-control" class="controls" hx-get="/kitchen/pause" hx-trigger="load, every 15s"></div>
+
         <div class="controls">
+            <div id="allday-toggle" class="icon-btn" style="font-size:1rem; width:auto; padding:0 15px;" onclick="toggleAllDay()" title="Show all-day item counts">Σ</div>
+            <select id="station-select" class="icon-btn" style="font-size:1rem; width:auto; padding:0 10px;" onchange="setStation(this.value)" title="Station for all-day counts">
+                <option value="">All stations</option>`)
+	for _, station := range kitchenStations() {
+		fmt.Fprintf(w, `
+                <option value="%s">%s</option>`, html.EscapeString(station), html.EscapeString(strings.ToUpper(station)))
+	}
+	fmt.Fprint(w, `
+            </select>
+            <div class="icon-btn" style="font-size:1rem; width:auto; padding:0 15px;" hx-get="/kitchen/recall" hx-target="body" hx-swap="beforeend" title="Search all orders">🔎 Recall</div>
+            <div class="icon-btn" style="font-size:1rem; width:auto; padding:0 15px;" hx-get="/kitchen/86" hx-target="body" hx-swap="beforeend" title="Mark items sold out">🚫 86</div>
+            <div class="icon-btn" style="font-size:1rem;" onclick="location.href='/kitchen/keymap/edit?screen=' + encodeURIComponent(kdsScreen)" title="Keyboard / bump-bar key map">⌨️</div>
             <div id="system-clock">--:--:--</div>
+            <a href="/display" target="_blank" class="icon-btn" style="text-decoration:none; font-size:1rem; width: auto; padding: 0 15px;" title="Open customer display board">📺</a>
             <a href="/" class="icon-btn" style="text-decoration:none; font-size:1rem; width: auto; padding: 0 15px;">Exit</a>
         </div>
     </header>
@@ -232,9 +379,16 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
 
     <div id="kds-container"
          hx-get="/kitchen/orders" 
-         hx-trigger="load, every 5s">
+         hx-trigger="load, every 5s"
+         hx-vals='js:{allday: localStorage.getItem("kds_allday") || "", station: localStorage.getItem("kds_station") || "", screen: kdsScreen}'>
     </div>
 
+    <script>
+        // Screen name (e.g. /kitchen?screen=grill) selects this screen's key map
+        const urlScreen = new URLSearchParams(location.search).get('screen');
+        if (urlScreen !== null) localStorage.setItem('kds_screen', urlScreen);
+        const kdsScreen = localStorage.getItem('kds_screen') || '';
+    </script>
     <script>
         let seenOrders = new Set();
         let isFirstLoad = true;
@@ -263,6 +417,120 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
         applyZoom();
         // ------------------
 
+        // --- ALL-DAY PANEL (per screen) ---
+        function applyAllDay() {
+            const on = localStorage.getItem('kds_allday') === '1';
+            document.getElementById('allday-toggle').classList.toggle('active-sound', on);
+            document.getElementById('station-select').value = localStorage.getItem('kds_station') || '';
+        }
+
+        function toggleAllDay() {
+            localStorage.setItem('kds_allday', localStorage.getItem('kds_allday') === '1' ? '' : '1');
+            applyAllDay();
+            refreshBoard();
+        }
+
+        function setStation(station) {
+            localStorage.setItem('kds_station', station);
+            refreshBoard();
+        }
+
+        // Re-fetch the board now instead of waiting for the next poll
+        function refreshBoard() {
+            htmx.ajax('GET', '/kitchen/orders', {source: '#kds-container', target: '#kds-container'});
+        }
+
+        applyAllDay();
+        // ------------------
+
+        // --- KEYBOARD / BUMP BAR ---
+        let keyMap = {};
+        let selectedId = null;
+
+        fetch('/kitchen/keymap?screen=' + encodeURIComponent(kdsScreen))
+            .then(r => r.json()).then(m => { keyMap = m; });
+
+        function activeTickets() {
+            return Array.from(document.querySelectorAll('.ticket:not(.completed-ticket)'));
+        }
+
+        function applySelection() {
+            const tickets = activeTickets();
+            if (tickets.length && !tickets.some(t => t.getAttribute('data-id') === selectedId)) {
+                selectedId = tickets[0].getAttribute('data-id');
+            }
+            document.querySelectorAll('.ticket').forEach(t => {
+                t.classList.toggle('selected', t.getAttribute('data-id') === selectedId);
+            });
+            const sel = document.querySelector('.ticket.selected');
+            if (sel) sel.scrollIntoView({block: 'nearest'});
+        }
+
+        function moveSelection(delta) {
+            const tickets = activeTickets();
+            if (!tickets.length) return;
+            let i = tickets.findIndex(t => t.getAttribute('data-id') === selectedId) + delta;
+            i = Math.max(0, Math.min(tickets.length - 1, i));
+            selectedId = tickets[i].getAttribute('data-id');
+            applySelection();
+        }
+
+        // Every ticket action goes through the server so it lands in the order history
+        function sendAction(action) {
+            htmx.ajax('POST', '/kitchen/action?action=' + action + '&id=' + (selectedId || ''),
+                {source: '#kds-container', target: '#kds-container'});
+        }
+
+        document.addEventListener('keydown', function(e) {
+            if (e.target.tagName === 'INPUT' || e.target.tagName === 'SELECT') return;
+            const key = e.key;
+            let handled = true;
+
+            if (/^[1-9]$/.test(key) && !Object.values(keyMap).includes(key)) {
+                const t = activeTickets()[parseInt(key) - 1];
+                if (t) { selectedId = t.getAttribute('data-id'); applySelection(); }
+            } else if (key === keyMap.select_next) moveSelection(1);
+            else if (key === keyMap.select_prev) moveSelection(-1);
+            else if (key === keyMap.bump && selectedId) sendAction('bump');
+            else if (key === keyMap.recall) sendAction('recall');
+            else if (key === keyMap.priority && selectedId) sendAction('priority');
+            else if (key === keyMap.vip && selectedId) sendAction('vip');
+            else if (key === keyMap.move_earlier && selectedId) sendAction('move_earlier');
+            else if (key === keyMap.move_later && selectedId) sendAction('move_later');
+            else if (key === keyMap.scroll_down) window.scrollBy(0, window.innerHeight / 2);
+            else if (key === keyMap.scroll_up) window.scrollBy(0, -window.innerHeight / 2);
+            else handled = false;
+
+            if (handled) e.preventDefault();
+        });
+        // ------------------
+
+        // --- DRAG & DROP REORDERING (saved on the server for all screens) ---
+        let dragId = null;
+        document.addEventListener('dragstart', e => {
+            const t = e.target.closest && e.target.closest('.ticket');
+            if (t) dragId = t.getAttribute('data-id');
+        });
+        document.addEventListener('dragover', e => {
+            const t = e.target.closest && e.target.closest('.ticket[draggable]');
+            if (!t || !dragId) return;
+            e.preventDefault();
+            document.querySelectorAll('.drag-over').forEach(el => el.classList.remove('drag-over'));
+            t.classList.add('drag-over');
+        });
+        document.addEventListener('drop', e => {
+            const t = e.target.closest && e.target.closest('.ticket[draggable]');
+            if (!t || !dragId) return;
+            e.preventDefault();
+            const beforeId = t.getAttribute('data-id');
+            if (beforeId !== dragId) {
+                htmx.ajax('POST', '/kitchen/move?id=' + dragId + '&before=' + beforeId,
+                    {source: '#kds-container', target: '#kds-container'});
+            }
+            dragId = null;
+        });
+        // ------------------
+
         function updateTime() {
             const now = new Date();
             document.getElementById('system-clock').innerText = now.toLocaleTimeString([], { hour12: true });
@@ -290,6 +558,7 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
         document.body.addEventListener('htmx:afterOnLoad', function(evt) {
             if (evt.target.id === 'kds-container') {
                 updateTime();
+                applySelection();
                 const tickets = document.querySelectorAll('.ticket:not(.completed-ticket)'); 
                 let hasNewOrder = false;
                 tickets.forEach(t => {
@@ -325,19 +594,33 @@ func handleKitchenPage(w http.ResponseWriter, r *http.Request) {
 // 2. Fetch Orders (No logic changes, just layout structure calls)
 func handleGetKitchenOrders(w http.ResponseWriter, r *http.Request) {
 	// (Database queries remain identical to your previous code)
-	activeQuery := `SELECT id, customer_name, total_amount, status, created_at FROM orders WHERE status != 'Completed' AND created_at >= datetime('now', '-24 hours') ORDER BY id ASC`
-	activeOrders := getOrdersByQuery(activeQuery)
+	activeOrders := getOrdersByQuery(activeOrdersQuery)
+	late := lateOrderIDs()
+	for i := range activeOrders {
+		activeOrders[i].Late = late[activeOrders[i].ID]
+	}
 
-	completedQuery := `SELECT id, customer_name, total_amount, status, created_at FROM orders WHERE status = 'Completed' AND created_at >= datetime('now', '-24 hours') ORDER BY id DESC LIMIT 4`
+	completedQuery := `SELECT ` + orderColumns + ` FROM orders WHERE status IN ('Completed', 'Picked Up') AND created_at >= datetime('now', '-24 hours') ORDER BY id DESC LIMIT 4`
 	completedOrders := getOrdersByQuery(completedQuery)
 
+	// Tickets are shown in the kitchen language, not the language the customer ordered in
+	tr := kitchenTranslator()
+
+	// Manager alert for tickets past their server-side SLA
+	renderSLABanner(w, "")
+
+	// Per-screen all-day counts (toggled in the KDS header, sent along with every request)
+	if r.FormValue("allday") == "1" {
+		renderAllDayPanel(w, activeOrders, r.FormValue("station"))
+	}
+
 	fmt.Fprint(w, `<div class="active-wrapper">`)
 	if len(activeOrders) == 0 {
 		fmt.Fprint(w, `<div style="text-align: center; color: #555; margin-top: 100px;"><h2>All Caught Up!</h2></div>`)
 	} else {
 		fmt.Fprint(w, `<div class="kitchen-grid">`)
 		for _, o := range activeOrders {
-			renderTicket(w, o, false)
+			renderTicket(w, o, false, tr)
 		}
 		fmt.Fprint(w, `</div>`)
 	}
@@ -346,15 +629,15 @@ func handleGetKitchenOrders(w http.ResponseWriter, r *http.Request) {
 	if len(completedOrders) > 0 {
 		fmt.Fprint(w, `<div class="completed-section"><h2 class="completed-header">Recently Completed</h2><div class="kitchen-grid">`)
 		for _, o := range completedOrders {
-			renderTicket(w, o, true)
+			renderTicket(w, o, true, tr)
 		}
 		fmt.Fprint(w, `</div></div>`)
 	}
 }
 
 // Helper to avoid code duplication
-func getOrdersByQuery(query string) []Order {
-	rows, err := db.Query(query)
+func getOrdersByQuery(query string, args ...interface{}) []Order {
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		fmt.Println("DB Error:", err)
 		return []Order{}
@@ -364,53 +647,103 @@ func getOrdersByQuery(query string) []Order {
 	var orders []Order
 	for rows.Next() {
 		var o Order
-		rows.Scan(&o.ID, &o.Customer, &o.Total, &o.Status, &o.CreatedAt)
+		rows.Scan(&o.ID, &o.Customer, &o.Total, &o.Status, &o.CreatedAt, &o.PickupNumber, &o.EstimatedAt, &o.Flag, &o.Phone, &o.KitchenAlert, &o.Channel, &o.Payment, &o.Allergy)
 
-		itemRows, _ := db.Query("SELECT product_name, options, price FROM order_items WHERE order_id = ?", o.ID)
+		itemRows, _ := db.Query("SELECT id, COALESCE(product_id, 0), product_name, COALESCE(options, ''), COALESCE(remarks, ''), price, COALESCE(voided, 0) FROM order_items WHERE order_id = ?", o.ID)
 		for itemRows.Next() {
 			var i OrderItem
-			itemRows.Scan(&i.Name, &i.Options, &i.Price)
+			itemRows.Scan(&i.ID, &i.ProductID, &i.Name, &i.Options, &i.Remarks, &i.Price, &i.Voided)
 			o.Items = append(o.Items, i)
 		}
 		itemRows.Close()
+		for idx := range o.Items {
+			o.Items[idx].Modifiers = loadModifiers(o.Items[idx].ID)
+		}
 		orders = append(orders, o)
 	}
 	return orders
 }
 
 // 3. Render Ticket
-func renderTicket(w http.ResponseWriter, o Order, isCompleted bool) {
+func renderTicket(w http.ResponseWriter, o Order, isCompleted bool, tr translator) {
 	cssClass := ""
 	btnText := "Complete Order"
-	targetStatus := "Completed"
+	targetStatus := StatusCompleted
 	btnClass := "btn-complete"
 
+	// Flagged tickets stand out; active tickets can be dragged to reorder the queue
+	dragAttrs := ""
+	flagBadge := ""
+	if !isCompleted {
+		dragAttrs = `draggable="true"`
+		switch o.Flag {
+		case FlagRush:
+			cssClass = "rush-ticket"
+			flagBadge = `<span class="flag-badge">⚡ RUSH</span>`
+		case FlagVIP:
+			cssClass = "vip-ticket"
+			flagBadge = `<span class="flag-badge">⭐ VIP</span>`
+		}
+		if o.Late {
+			cssClass += " late-ticket"
+		}
+		if o.Allergy != "" {
+			cssClass += " allergy-ticket"
+		}
+	}
+
 	if isCompleted {
 		cssClass = "completed-ticket"
 		btnText = "↩ Restore"
-		targetStatus = "Paid" // Returns to active stack
+		targetStatus = StatusPaid // Returns to active stack
 		btnClass = "btn-restore"
 	}
 
-	// Date formatting logic
-	t, err := time.Parse(time.RFC3339, o.CreatedAt)
-	if err != nil {
-		t, _ = time.Parse("2006-01-02 15:04:05", o.CreatedAt)
+	// Ready orders can be handed over, which clears them from the display board
+	pickupBtn := ""
+	if o.Status == StatusCompleted {
+		pickupBtn = fmt.Sprintf(`
+			<button class="btn-kds btn-pickup"
+				hx-post="/kitchen/status?id=%d&status=%s"
+				hx-target="#kds-container"
+				hx-swap="innerHTML">
+				✔ Picked Up
+			</button>`, o.ID, url.QueryEscape(StatusPickedUp))
 	}
 
+	flagBtns := ""
+	if !isCompleted {
+		flagBtns = fmt.Sprintf(`
+			<div class="flag-btns">
+				<button class="btn-kds btn-flag" hx-post="/kitchen/flag?id=%d&flag=rush" hx-target="#kds-container">⚡ Rush</button>
+				<button class="btn-kds btn-flag" hx-post="/kitchen/flag?id=%d&flag=vip" hx-target="#kds-container">⭐ VIP</button>
+				<button class="btn-kds btn-flag" hx-get="/orders/edit?id=%d" hx-target="body" hx-swap="beforeend">✎ Edit</button>
+			</div>`, o.ID, o.ID, o.ID)
+	}
+
+	// Date formatting logic
+	t := parseDBTime(o.CreatedAt)
+
 	displayTime := o.CreatedAt
 	if !t.IsZero() {
 		displayTime = fmt.Sprintf("Time: %s", t.Local().Format("3:04 pm"))
 	}
 
+	// Loud banner for orders changed after they hit the board
+	alertBanner := ""
+	if o.KitchenAlert != "" && !isCompleted {
+		alertBanner = fmt.Sprintf(`<div class="alert-banner alert-%s" hx-post="/kitchen/ack?id=%d" hx-target="#kds-container" title="Tap to acknowledge">⚠ %s — tap to acknowledge</div>`,
+			strings.ToLower(o.KitchenAlert), o.ID, o.KitchenAlert)
+	}
+
 	// --- HUGE ID CHANGE BELOW ---
 	// changed font-size:1.3rem to 3rem and added line-height:1 for better fit
 	fmt.Fprintf(w, `
-	<div class="ticket %s" id="order-%d" data-id="%d" data-created="%s">
+	<div class="ticket %s" id="order-%d" data-id="%d" data-created="%s" %s>
 		<div class="ticket-header">
-			<span style="font-weight:bold; font-size:3rem; line-height:1;">#%d</span>
-			<span style="font-weight:bold; font-size:1.2rem;">%s</span>
-		</div>
+			<span style="font-weight:bold; font-size:3rem; line-height:1;">#%s</span>
+			<span style="font-weight:bold; font-size:1.2rem; text-align:right;">%s<br>%s</span>
+		</div>%s%s
 		<div class="ticket-body">
 			<div class="ticket-meta">
 				<span>%s</span> 
@@ -421,17 +754,33 @@ func renderTicket(w http.ResponseWriter, o Order, isCompleted bool) {
 		o.ID,
 		o.ID,
 		o.CreatedAt,
-		o.ID, // This ID is now huge
-		o.Customer,
+		dragAttrs,
+		o.Number(), // Short pickup number, called out at the counter
+		html.EscapeString(o.Customer),
+		flagBadge,
+		alertBanner,
+		allergyBanner(o),
 		displayTime,
 	)
 
 	for _, item := range o.Items {
-		opts := ""
-		if item.Options != "" {
-			opts = fmt.Sprintf(`<span class="ticket-opt">+ %s</span>`, item.Options)
+		if item.Voided {
+			fmt.Fprintf(w, `<li class="void-item"><s>%s</s> VOID</li>`, tr.ProductName(item.ProductID, item.Name))
+			continue
 		}
-		fmt.Fprintf(w, `<li>%s %s</li>`, item.Name, opts)
+		fmt.Fprintf(w, `<li>%s %s</li>`, tr.ProductName(item.ProductID, item.Name), ticketOptionsHTML(item, tr))
+	}
+
+	// Cancelled orders only need acknowledging; everything else is unchanged
+	if o.Status == StatusCancelled {
+		fmt.Fprintf(w, `
+			</ul>
+		</div>
+		<div class="action-area">
+			<button class="btn-kds btn-void" hx-post="/kitchen/ack?id=%d" hx-target="#kds-container">✔ Got it — don't cook</button>
+		</div>
+	</div>`, o.ID)
+		return
 	}
 
 	fmt.Fprintf(w, `
@@ -443,9 +792,9 @@ func renderTicket(w http.ResponseWriter, o Order, isCompleted bool) {
 				hx-target="#kds-container" 
 				hx-swap="innerHTML">
 				%s
-			</button>
+			</button>%s%s
 		</div>
-	</div>`, btnClass, o.ID, targetStatus, btnText)
+	</div>`, btnClass, o.ID, url.QueryEscape(targetStatus), btnText, pickupBtn, flagBtns)
 }
 
 // 4. Status Handler
@@ -453,11 +802,48 @@ func handleKitchenStatus(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
 	newStatus := r.URL.Query().Get("status")
 
-	_, err := db.Exec("UPDATE orders SET status = ? WHERE id = ?", newStatus, id)
-	if err != nil {
+	if orderLocked(id) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+
+	if err := updateOrderStatus(id, newStatus); err != nil {
 		fmt.Printf("Error updating: %v", err)
 	}
 
 	// Reload the entire board
 	handleGetKitchenOrders(w, r)
 }
+
+// parseDBTime reads SQLite timestamps, which come back either as RFC3339 or "2006-01-02 15:04:05" (UTC)
+func parseDBTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, _ = time.Parse("2006-01-02 15:04:05", s)
+	}
+	return t
+}
+
+// updateOrderStatus changes an order's status and records the transition in order_events
+func updateOrderStatus(id string, newStatus string) error {
+	_, err := db.Exec("UPDATE orders SET status = ? WHERE id = ?", newStatus, id)
+	if err != nil {
+		return err
+	}
+	if newStatus == StatusCompleted {
+		recordPrepTimes(id)
+	}
+	if newStatus != StatusPaid {
+		resolveSLABreaches(id)
+	} else {
+		// Reopened and restored tickets drop their old manual position instead of jumping ahead
+		db.Exec("UPDATE orders SET queue_position = NULL WHERE id = ?", id)
+	}
+	return recordOrderEvent(id, "status", newStatus)
+}
+
+// recordOrderEvent appends an entry to the order's history
+func recordOrderEvent(orderID interface{}, event, detail string) error {
+	_, err := db.Exec("INSERT INTO order_events (order_id, event, detail) VALUES (?, ?, ?)", orderID, event, detail)
+	return err
+}
@@ -12,18 +12,25 @@ import (
 
 func main() {
 	var err error
-	db, err = sql.Open("sqlite3", "./pizza.db")
+	// Transactions take the write lock up front (BEGIN IMMEDIATE), so two checkouts of the
+	// same cart queue behind each other instead of one failing with "database is locked"
+	db, err = sql.Open("sqlite3", "./pizza.db?_txlock=immediate")
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 	initDB(db)
+	initMenuSearch()
+	startSLAMonitor()
+	startDayRollover()
 
 	// --- 1. LANDING PAGE SERVER (Port 9002) ---
 	landingMux := http.NewServeMux()
 	landingMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		tmpl := template.Must(template.ParseFiles("landing_page.html"))
-		tmpl.Execute(w, nil)
+		tmpl.Execute(w, map[string]interface{}{
+			"WaitMinutes": currentWaitMinutes(),
+		})
 	})
 	landingMux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("./images"))))
 
@@ -33,31 +40,20 @@ func main() {
 	// Customer Routes
 	orderMux.HandleFunc("/", handleIndex)
 	orderMux.HandleFunc("/menu", handleGetMenu)
+	orderMux.HandleFunc("/cart", handleGetCart)
 	orderMux.HandleFunc("/cart/add", handleAddToCart)
 	orderMux.HandleFunc("/cart/clear", handleClearCart)
+	orderMux.HandleFunc("/cart/remove", handleRemoveFromCart)
+	orderMux.HandleFunc("/menu/sold-out", handleMenuSoldOut)
+	orderMux.HandleFunc("/menu/nutrition", handleMenuNutrition)
 	orderMux.HandleFunc("/checkout", handleCheckout)
+	orderMux.HandleFunc("/ordering/banner", handleOrderingBanner)
 
-	// Success Page
-	orderMux.HandleFunc("/success", func(w http.ResponseWriter, r *http.Request) {
-		cart = []CartItem{}
-		fmt.Fprint(w, `
-			<!DOCTYPE html>
-			<html lang="en">
-			<head>
-				<meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0">
-				<script src="https://cdn.tailwindcss.com"></script>
-			</head>
-			<body class="bg-gray-50 flex items-center justify-center h-screen">
-				<div class="bg-white p-8 rounded-xl shadow-lg text-center max-w-md">
-					<div class="text-6xl mb-4">🎉</div>
-					<h1 class="text-2xl font-bold text-gray-800 mb-2">Payment Successful!</h1>
-					<p class="text-gray-600 mb-6">Your order has been sent to the kitchen. Pickup in ~20 mins.</p>
-					<a href='/' class="inline-block bg-orange-600 text-white px-6 py-2 rounded-lg font-medium hover:bg-orange-700 transition">Order More</a>
-				</div>
-			</body>
-			</html>
-		`)
-	})
+	// Success & Order Status Pages
+	orderMux.HandleFunc("/success", handleSuccess)
+	orderMux.HandleFunc("/order", handleOrderStatusPage)
+	orderMux.HandleFunc("/order/status", handleOrderStatus)
+	orderMux.HandleFunc("/order/reorder", handleReorder)
 
 	// Static Assets
 	orderMux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("./images"))))
@@ -68,11 +64,78 @@ func main() {
 	orderMux.HandleFunc("/admin/create", handleAdminCreateProduct)
 	orderMux.HandleFunc("/admin/delete", handleAdminDeleteProduct)
 	orderMux.HandleFunc("/admin/generate-image", handleAdminGenerateImage)
+	orderMux.HandleFunc("/admin/alerts", handleAdminAlerts)
+	orderMux.HandleFunc("/admin/orders", handleAdminOrders)
+	orderMux.HandleFunc("/admin/orders/export", handleAdminOrdersExport)
+	orderMux.HandleFunc("/admin/orders/detail", handleAdminOrderDetail)
+	orderMux.HandleFunc("/admin/orders/refund", handleAdminOrderRefund)
+	orderMux.HandleFunc("/admin/orders/cancel", handleAdminOrderCancel)
+	orderMux.HandleFunc("/admin/orders/receipt", handleAdminOrderReceipt)
+	orderMux.HandleFunc("/admin/reports", handleAdminReports)
+	orderMux.HandleFunc("/admin/reports/export", handleAdminReportsExport)
+	orderMux.HandleFunc("/admin/reports/menu", handleAdminMenuEngineering)
+	orderMux.HandleFunc("/admin/inventory", handleAdminInventory)
+	orderMux.HandleFunc("/admin/inventory/ingredient", handleAdminIngredientCreate)
+	orderMux.HandleFunc("/admin/inventory/adjust", handleAdminStockAdjust)
+	orderMux.HandleFunc("/admin/inventory/cost", handleAdminIngredientCost)
+	orderMux.HandleFunc("/admin/inventory/recipe", handleAdminRecipeAdd)
+	orderMux.HandleFunc("/admin/inventory/recipe/delete", handleAdminRecipeDelete)
+	orderMux.HandleFunc("/admin/purchasing", handleAdminPurchasing)
+	orderMux.HandleFunc("/admin/purchasing/supplier", handleAdminSupplierCreate)
+	orderMux.HandleFunc("/admin/purchasing/ingredient", handleAdminIngredientSupply)
+	orderMux.HandleFunc("/admin/purchasing/suggest", handleAdminPOSuggest)
+	orderMux.HandleFunc("/admin/purchasing/po", handleAdminPODetail)
+	orderMux.HandleFunc("/admin/purchasing/po/line", handleAdminPOLineAdd)
+	orderMux.HandleFunc("/admin/purchasing/po/line/delete", handleAdminPOLineDelete)
+	orderMux.HandleFunc("/admin/purchasing/po/send", handleAdminPOSend)
+	orderMux.HandleFunc("/admin/purchasing/po/print", handleAdminPOPrint)
+	orderMux.HandleFunc("/admin/purchasing/po/export", handleAdminPOExport)
+	orderMux.HandleFunc("/admin/purchasing/receive", handleAdminPOReceive)
+	orderMux.HandleFunc("/admin/schedules", handleAdminSchedules)
+	orderMux.HandleFunc("/admin/schedules/add", handleAdminScheduleAdd)
+	orderMux.HandleFunc("/admin/schedules/delete", handleAdminScheduleDelete)
+	orderMux.HandleFunc("/admin/modifiers/dietary", handleAdminModifierDietary)
+	orderMux.HandleFunc("/admin/languages/kitchen", handleAdminKitchenLanguage)
+	orderMux.HandleFunc("/admin/languages/label", handleAdminLabelTranslation)
+	orderMux.HandleFunc("/admin/nutrition", handleAdminNutrition)
+	orderMux.HandleFunc("/admin/nutrition/save", handleAdminNutritionSave)
+	orderMux.HandleFunc("/admin/nutrition/import", handleAdminNutritionImport)
+	orderMux.HandleFunc("/admin/nutrition/export", handleAdminNutritionExport)
+	orderMux.HandleFunc("/admin/close", handleAdminClose)
+	orderMux.HandleFunc("/admin/close/float", handleAdminCloseFloat)
+	orderMux.HandleFunc("/admin/close/submit", handleAdminCloseSubmit)
+	orderMux.HandleFunc("/admin/close/print", handleAdminClosePrint)
+	orderMux.HandleFunc("/admin/close/day-start", handleAdminDayStart)
 
 	// Kitchen Routes
 	orderMux.HandleFunc("/kitchen", handleKitchenPage)
 	orderMux.HandleFunc("/kitchen/orders", handleGetKitchenOrders)
 	orderMux.HandleFunc("/kitchen/status", handleKitchenStatus)
+	orderMux.HandleFunc("/kitchen/pause", handleKitchenPause)
+	orderMux.HandleFunc("/kitchen/86", handleKitchen86Panel)
+	orderMux.HandleFunc("/kitchen/86/toggle", handleKitchen86Toggle)
+	orderMux.HandleFunc("/kitchen/action", handleKitchenAction)
+	orderMux.HandleFunc("/kitchen/flag", handleKitchenFlag)
+	orderMux.HandleFunc("/kitchen/move", handleKitchenMove)
+	orderMux.HandleFunc("/kitchen/recall", handleKitchenRecallPanel)
+	orderMux.HandleFunc("/kitchen/recall/search", handleKitchenRecallSearch)
+	orderMux.HandleFunc("/kitchen/recall/order", handleKitchenRecallOrder)
+	orderMux.HandleFunc("/kitchen/recall/reopen", handleKitchenRecallReopen)
+	orderMux.HandleFunc("/kitchen/ticket", handleKitchenTicketPrint)
+	orderMux.HandleFunc("/kitchen/ack", handleKitchenAck)
+
+	// Staff Order Changes (cancel / void / amend)
+	orderMux.HandleFunc("/orders/edit", handleOrderEditPanel)
+	orderMux.HandleFunc("/orders/amend-item", handleAmendOrderItem)
+	orderMux.HandleFunc("/orders/void-item", handleVoidOrderItem)
+	orderMux.HandleFunc("/orders/cancel", handleCancelOrder)
+	orderMux.HandleFunc("/orders/collect", handleCollectSurcharge)
+	orderMux.HandleFunc("/kitchen/keymap", handleKitchenKeyMap)
+	orderMux.HandleFunc("/kitchen/keymap/edit", handleKitchenKeyMapEdit)
+
+	// Customer Display Board
+	orderMux.HandleFunc("/display", handleDisplayPage)
+	orderMux.HandleFunc("/display/board", handleDisplayBoard)
 
 	go func() {
 		fmt.Println("SEO Landing Page: http://localhost:9002")
@@ -85,7 +148,25 @@ func main() {
 
 // handleIndex parses both the layout (index.html) and the specific view (customer.html)
 func handleIndex(w http.ResponseWriter, r *http.Request) {
+	// Kiosks open the menu once with ?channel=kiosk so their orders get the kiosk prefix
+	if ch := r.URL.Query().Get("channel"); ch != "" {
+		if _, ok := channelPrefixes[ch]; ok {
+			http.SetCookie(w, &http.Cookie{Name: "channel", Value: ch, Path: "/", MaxAge: 365 * 24 * 3600})
+		}
+	}
+
+	// The language switcher links to /?lang=ms; the choice is kept in a cookie
+	lang := r.URL.Query().Get("lang")
+	if validLang(lang) {
+		http.SetCookie(w, &http.Cookie{Name: "lang", Value: lang, Path: "/", MaxAge: 365 * 24 * 3600})
+	} else {
+		lang = requestLang(r)
+	}
+
 	// We parse both files so index.html can use {{template "content" .}} defined in customer.html
-	tmpl := template.Must(template.ParseFiles("index.html", "customer.html"))
+	tmpl := template.Must(template.New("index.html").Funcs(template.FuncMap{
+		"t":    func(s string) string { return T(lang, s) },
+		"lang": func() string { return lang },
+	}).ParseFiles("index.html", "customer.html"))
 	tmpl.Execute(w, nil)
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notifier delivers manager alerts (late tickets, ...) to some outside channel
+type Notifier interface {
+	Notify(subject, message string) error
+}
+
+// Set to a Slack/Discord/Teams-style incoming webhook to push alerts to phones
+const alertWebhookURL = ""
+
+// notifiers receive every alert; add more implementations here (SMS, email, ...)
+var notifiers = []Notifier{logNotifier{}}
+
+func init() {
+	if alertWebhookURL != "" {
+		notifiers = append(notifiers, webhookNotifier{URL: alertWebhookURL})
+	}
+}
+
+// notify sends an alert through all configured notifiers
+func notify(subject, message string) {
+	for _, n := range notifiers {
+		if err := n.Notify(subject, message); err != nil {
+			log.Printf("Notifier error: %v", err)
+		}
+	}
+}
+
+// logNotifier writes alerts to the server log
+type logNotifier struct{}
+
+func (logNotifier) Notify(subject, message string) error {
+	log.Printf("[ALERT] %s: %s", subject, message)
+	return nil
+}
+
+// webhookNotifier posts alerts as JSON {"text": "..."} to a webhook URL
+type webhookNotifier struct {
+	URL string
+}
+
+func (n webhookNotifier) Notify(subject, message string) error {
+	body, _ := json.Marshal(map[string]string{"text": subject + ": " + message})
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Nutrition facts are optional: a product has them once its calories are recorded.
+// Add-ons carry deltas (per add-on, on top of the item), so a cart line's facts are the
+// product's plus those of its chosen options. Options without a record add nothing.
+
+type Nutrition struct {
+	Known                                 bool
+	Calories, Protein, Fat, Carbs, Sodium float64
+}
+
+// nutritionFields are the stored columns, in CSV order, with their display label and unit
+var nutritionFields = []struct{ Column, Label, Unit string }{
+	{"calories", "Calories", "kcal"},
+	{"protein_g", "Protein", "g"},
+	{"fat_g", "Fat", "g"},
+	{"carbs_g", "Carbs", "g"},
+	{"sodium_mg", "Sodium", "mg"},
+}
+
+func (n Nutrition) Values() []float64 {
+	return []float64{n.Calories, n.Protein, n.Fat, n.Carbs, n.Sodium}
+}
+
+// Plus adds an add-on's delta (or another line's facts) to n
+func (n Nutrition) Plus(o Nutrition) Nutrition {
+	n.Calories += o.Calories
+	n.Protein += o.Protein
+	n.Fat += o.Fat
+	n.Carbs += o.Carbs
+	n.Sodium += o.Sodium
+	return n
+}
+
+// nutritionSelect reads the nutrition columns of the surrounding row
+const nutritionSelect = `calories IS NOT NULL, COALESCE(calories, 0), COALESCE(protein_g, 0), COALESCE(fat_g, 0), COALESCE(carbs_g, 0), COALESCE(sodium_mg, 0)`
+
+// productNutrition returns the facts of every product that has them recorded
+func productNutrition() map[int]Nutrition {
+	out := map[int]Nutrition{}
+	rows, err := db.Query("SELECT id, " + nutritionSelect + " FROM products WHERE calories IS NOT NULL")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var n Nutrition
+		rows.Scan(&id, &n.Known, &n.Calories, &n.Protein, &n.Fat, &n.Carbs, &n.Sodium)
+		out[id] = n
+	}
+	return out
+}
+
+// modifierNutrition returns the deltas of every add-on that has them recorded
+func modifierNutrition() map[string]Nutrition {
+	out := map[string]Nutrition{}
+	rows, err := db.Query("SELECT modifier_name, " + nutritionSelect + " FROM modifier_attributes WHERE calories IS NOT NULL")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var n Nutrition
+		rows.Scan(&name, &n.Known, &n.Calories, &n.Protein, &n.Fat, &n.Carbs, &n.Sodium)
+		out[name] = n
+	}
+	return out
+}
+
+// lineNutrition is one configured line's facts: the product's plus each option's delta.
+// Unknown when the product itself has no facts recorded.
+func lineNutrition(productID int, mods []Modifier, products map[int]Nutrition, modifiers map[string]Nutrition) Nutrition {
+	n := products[productID]
+	if !n.Known {
+		return n
+	}
+	for _, m := range mods {
+		n = n.Plus(modifiers[m.Name])
+	}
+	return n
+}
+
+func formatNutrient(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// nutritionFactsHTML is the table in a menu card's expandable nutrition view
+func nutritionFactsHTML(n Nutrition, tr translator) string {
+	if !n.Known {
+		return `<p class="text-gray-400">` + tr.T("Nutrition info not available") + `</p>`
+	}
+	var b strings.Builder
+	b.WriteString(`<table class="w-full"><tbody>`)
+	for i, v := range n.Values() {
+		f := nutritionFields[i]
+		fmt.Fprintf(&b, `<tr class="border-t border-gray-100"><td class="py-0.5">%s</td><td class="text-right font-semibold">%.0f %s</td></tr>`,
+			tr.T(f.Label), v, f.Unit)
+	}
+	b.WriteString(`</tbody></table>`)
+	return b.String()
+}
+
+// nutritionDetails is the expandable nutrition view on a menu card. It follows the card's
+// chosen options, re-fetching the facts whenever they change.
+func nutritionDetails(p Product, n Nutrition, tr translator) string {
+	if !n.Known {
+		return ""
+	}
+	return fmt.Sprintf(`
+		<details class="mt-3 text-xs text-gray-600">
+			<summary class="cursor-pointer select-none font-medium">🥗 %s · <span class="nutrition-kcal">%.0f kcal</span></summary>
+			<div class="nutrition-facts mt-2" hx-get="/menu/nutrition?id=%d" hx-include="closest form" hx-trigger="change from:closest form">%s</div>
+		</details>`, tr.T("Nutrition"), n.Calories, p.ID, nutritionFactsHTML(n, tr))
+}
+
+// handleMenuNutrition renders a product's facts for the options currently chosen on its card
+func handleMenuNutrition(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	n := lineNutrition(id, selectedModifiers(r), productNutrition(), modifierNutrition())
+	fmt.Fprint(w, nutritionFactsHTML(n, loadTranslator(requestLang(r))))
+}
+
+// cartNutrition sums the cart's lines; missing counts lines whose product has no facts
+func cartNutrition(items []CartItem) (total Nutrition, missing int) {
+	products, modifiers := productNutrition(), modifierNutrition()
+	for _, item := range items {
+		n := lineNutrition(item.ProductID, item.Options, products, modifiers)
+		if !n.Known {
+			missing++
+			continue
+		}
+		total = total.Plus(n)
+		total.Known = true
+	}
+	return total, missing
+}
+
+// cartNutritionHTML is the nutrition summary under the cart totals
+func cartNutritionHTML(items []CartItem, tr translator) string {
+	total, missing := cartNutrition(items)
+	if !total.Known {
+		return ""
+	}
+	var parts []string
+	for i, v := range total.Values() {
+		f := nutritionFields[i]
+		if f.Unit == "kcal" {
+			parts = append(parts, fmt.Sprintf("%.0f kcal", v))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%.0f%s %s", v, f.Unit, strings.ToLower(tr.T(f.Label))))
+	}
+	note := ""
+	if missing > 0 {
+		note = fmt.Sprintf(`<div class="text-gray-400">%s</div>`, fmt.Sprintf(tr.T("Excludes %d item(s) without nutrition info"), missing))
+	}
+	return fmt.Sprintf(`
+		<div class="mt-2 text-xs text-gray-500 text-center">
+			🥗 %s: %s%s
+		</div>`, tr.T("Nutrition"), strings.Join(parts, " · "), note)
+}
+
+// parseNutrition reads a nutrition row from form or CSV values, in nutritionFields order.
+// Blank calories clears the record; other blanks count as 0. Deltas may be negative.
+func parseNutrition(values []string, allowNegative bool) (Nutrition, error) {
+	var n Nutrition
+	if len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+		return n, nil
+	}
+	n.Known = true
+	parsed := make([]float64, len(nutritionFields))
+	for i := range nutritionFields {
+		if i >= len(values) || strings.TrimSpace(values[i]) == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(values[i]), 64)
+		if err != nil || (v < 0 && !allowNegative) {
+			return n, fmt.Errorf("invalid %s %q", nutritionFields[i].Column, values[i])
+		}
+		parsed[i] = v
+	}
+	n.Calories, n.Protein, n.Fat, n.Carbs, n.Sodium = parsed[0], parsed[1], parsed[2], parsed[3], parsed[4]
+	return n, nil
+}
+
+// nutritionArgs are the column values to store; NULL when the record is cleared
+func nutritionArgs(n Nutrition) []interface{} {
+	args := make([]interface{}, len(nutritionFields))
+	if n.Known {
+		for i, v := range n.Values() {
+			args[i] = v
+		}
+	}
+	return args
+}
+
+// saveProductNutrition stores a product's facts, matched by name when id is 0 (CSV rows)
+func saveProductNutrition(ex execer, id int, name string, n Nutrition) error {
+	query := `UPDATE products SET calories = ?, protein_g = ?, fat_g = ?, carbs_g = ?, sodium_mg = ? WHERE id = ?`
+	args := append(nutritionArgs(n), id)
+	if id == 0 {
+		query = `UPDATE products SET calories = ?, protein_g = ?, fat_g = ?, carbs_g = ?, sodium_mg = ? WHERE LOWER(name) = LOWER(?)`
+		args = append(nutritionArgs(n), name)
+	}
+	res, err := ex.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return fmt.Errorf("unknown product %q", orDefault(name, strconv.Itoa(id)))
+	}
+	return nil
+}
+
+// saveModifierNutrition stores an add-on's deltas
+func saveModifierNutrition(ex execer, name string, n Nutrition) error {
+	_, err := ex.Exec(`INSERT INTO modifier_attributes (modifier_name, calories, protein_g, fat_g, carbs_g, sodium_mg) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(modifier_name) DO UPDATE SET calories = excluded.calories, protein_g = excluded.protein_g,
+		fat_g = excluded.fat_g, carbs_g = excluded.carbs_g, sodium_mg = excluded.sodium_mg`,
+		append([]interface{}{name}, nutritionArgs(n)...)...)
+	return err
+}
+
+// nutritionInputs renders the five number inputs of an admin nutrition row, tied to the row's form
+func nutritionInputs(n Nutrition, formID string) string {
+	var b strings.Builder
+	for i, v := range n.Values() {
+		f := nutritionFields[i]
+		value := ""
+		if n.Known {
+			value = formatNutrient(v)
+		}
+		fmt.Fprintf(&b, `<td class="py-1"><input form="%s" type="number" step="any" name="%s" value="%s" placeholder="%s" class="w-24 border rounded px-2 py-1"></td>`,
+			formID, f.Column, value, f.Unit)
+	}
+	return b.String()
+}
+
+// handleAdminNutrition lists product facts and add-on deltas for editing, with CSV import/export
+func handleAdminNutrition(w http.ResponseWriter, r *http.Request) {
+	products := productNutrition()
+	modifiers := modifierNutrition()
+
+	adminPageStart(w, "Nutrition")
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		fmt.Fprintf(w, `<div class="bg-green-50 border border-green-200 text-green-800 rounded p-3">%s</div>`, html.EscapeString(msg))
+	}
+
+	header := `<tr class="text-left text-gray-500"><th class="py-1">Item</th>`
+	for _, f := range nutritionFields {
+		header += fmt.Sprintf(`<th>%s (%s)</th>`, f.Label, f.Unit)
+	}
+	header += `<th></th></tr>`
+
+	fmt.Fprintf(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">🥗 Products</h2>
+        <p class="text-sm text-gray-500 mb-4">Per item as served. Leave calories blank to hide nutrition for an item.</p>
+        <table class="w-full text-sm"><thead>%s</thead><tbody>`, header)
+	rows, err := db.Query("SELECT id, name FROM products ORDER BY category, name")
+	if err == nil {
+		for rows.Next() {
+			var id int
+			var name string
+			rows.Scan(&id, &name)
+			fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 font-semibold">%s</td>%s
+                <td class="text-right"><button form="nutrition-p%d" class="bg-gray-900 text-white rounded px-3 py-1">Save</button>
+                <form id="nutrition-p%d" method="post" action="/admin/nutrition/save"><input type="hidden" name="id" value="%d"></form></td></tr>`,
+				html.EscapeString(name), nutritionInputs(products[id], fmt.Sprintf("nutrition-p%d", id)), id, id, id)
+		}
+		rows.Close()
+	}
+	fmt.Fprintf(w, `</tbody></table>
+    </section>
+
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">➕ Add-ons</h2>
+        <p class="text-sm text-gray-500 mb-4">Added to the item's facts when the add-on is chosen. Negative values are allowed (e.g. less sugar).</p>
+        <table class="w-full text-sm"><thead>%s</thead><tbody>`, header)
+	for i, name := range knownModifiers() {
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 font-semibold">%s</td>%s
+                <td class="text-right"><button form="nutrition-m%d" class="bg-gray-900 text-white rounded px-3 py-1">Save</button>
+                <form id="nutrition-m%d" method="post" action="/admin/nutrition/save"><input type="hidden" name="modifier" value="%s"></form></td></tr>`,
+			html.EscapeString(name), nutritionInputs(modifiers[name], fmt.Sprintf("nutrition-m%d", i)), i, i, html.EscapeString(name))
+	}
+	fmt.Fprint(w, `</tbody></table>
+    </section>
+
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">📄 Bulk entry (CSV)</h2>
+        <p class="text-sm text-gray-500 mb-4">Columns: <code>type,name,calories,protein_g,fat_g,carbs_g,sodium_mg</code> where type is <code>product</code> or <code>modifier</code> and products are matched by name.
+            <a href="/admin/nutrition/export" class="text-blue-600 hover:underline">Download current values</a> to use as a template.</p>
+        <form method="post" action="/admin/nutrition/import" enctype="multipart/form-data" class="flex flex-wrap items-end gap-3 text-sm">
+            <input type="file" name="csv" accept=".csv,text/csv" class="text-sm">
+            <span class="text-gray-400">or paste</span>
+            <textarea name="csv_text" rows="3" placeholder="product,Margherita,850,36,30,104,1600" class="flex-grow border rounded px-2 py-1 font-mono text-xs"></textarea>
+            <button class="bg-orange-600 text-white rounded px-3 py-1.5 font-semibold">Import</button>
+        </form>
+    </section>`)
+	adminPageEnd(w)
+}
+
+func handleAdminNutritionSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var values []string
+	for _, f := range nutritionFields {
+		values = append(values, r.FormValue(f.Column))
+	}
+	modifier := r.FormValue("modifier")
+	n, err := parseNutrition(values, modifier != "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if modifier != "" {
+		err = saveModifierNutrition(db, modifier, n)
+	} else {
+		id, _ := strconv.Atoi(r.FormValue("id"))
+		err = saveProductNutrition(db, id, "", n)
+	}
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/nutrition?msg="+url.QueryEscape("Nutrition saved"), http.StatusSeeOther)
+}
+
+// handleAdminNutritionImport applies a CSV of product facts and add-on deltas. The whole file
+// is applied in one transaction, so a bad row leaves everything as it was.
+func handleAdminNutritionImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(1 << 20)
+	var src io.Reader = strings.NewReader(r.FormValue("csv_text"))
+	if file, _, err := r.FormFile("csv"); err == nil {
+		defer file.Close()
+		src = file
+	}
+	in := csv.NewReader(src)
+	in.FieldsPerRecord = -1
+	records, err := in.ReadAll()
+	if err != nil {
+		http.Error(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Add-on rows must name an existing add-on, so a typo can't create a new one
+	known := map[string]bool{}
+	for _, name := range knownModifiers() {
+		known[name] = true
+	}
+
+	imported := 0
+	for i, rec := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "type") {
+			continue // header
+		}
+		if len(rec) < 3 {
+			http.Error(w, fmt.Sprintf("Line %d: expected type, name and nutrition values — nothing was imported", i+1), http.StatusBadRequest)
+			return
+		}
+		kind, name := strings.ToLower(strings.TrimSpace(rec[0])), strings.TrimSpace(rec[1])
+		n, err := parseNutrition(rec[2:], kind == "modifier")
+		if err == nil {
+			switch kind {
+			case "product":
+				err = saveProductNutrition(tx, 0, name, n)
+			case "modifier":
+				if known[name] {
+					err = saveModifierNutrition(tx, name, n)
+				} else {
+					err = fmt.Errorf("unknown add-on %q", name)
+				}
+			default:
+				err = fmt.Errorf("unknown type %q", rec[0])
+			}
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Line %d: %v — nothing was imported", i+1, err), http.StatusBadRequest)
+			return
+		}
+		imported++
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/nutrition?msg="+url.QueryEscape(fmt.Sprintf("Imported %d rows", imported)), http.StatusSeeOther)
+}
+
+// handleAdminNutritionExport downloads the current facts in the import format
+func handleAdminNutritionExport(w http.ResponseWriter, r *http.Request) {
+	products := productNutrition()
+	modifiers := modifierNutrition()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="nutrition.csv"`)
+	out := csv.NewWriter(w)
+	header := []string{"type", "name"}
+	for _, f := range nutritionFields {
+		header = append(header, f.Column)
+	}
+	out.Write(header)
+
+	record := func(kind, name string, n Nutrition) []string {
+		rec := []string{kind, name}
+		for _, v := range n.Values() {
+			if n.Known {
+				rec = append(rec, formatNutrient(v))
+			} else {
+				rec = append(rec, "")
+			}
+		}
+		return rec
+	}
+	rows, err := db.Query("SELECT id, name FROM products ORDER BY category, name")
+	if err == nil {
+		for rows.Next() {
+			var id int
+			var name string
+			rows.Scan(&id, &name)
+			out.Write(record("product", name, products[id]))
+		}
+		rows.Close()
+	}
+	for _, name := range knownModifiers() {
+		out.Write(record("modifier", name, modifiers[name]))
+	}
+	out.Flush()
+}
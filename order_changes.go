@@ -0,0 +1,477 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Reason codes staff pick when cancelling or changing an order
+var changeReasons = []string{
+	"Customer request",
+	"Wrong item entered",
+	"Out of stock",
+	"Kitchen error",
+	"Duplicate order",
+	"Other",
+}
+
+// Loud ticket banners shown on the KDS until the kitchen acknowledges them
+const (
+	AlertModified = "MODIFIED"
+	AlertVoid     = "VOID"
+)
+
+func reasonSelect() string {
+	opts := ""
+	for _, r := range changeReasons {
+		opts += fmt.Sprintf(`<option>%s</option>`, r)
+	}
+	return `<select name="reason" required>` + opts + `</select>`
+}
+
+// handleOrderEditPanel lets staff amend, void or cancel an order (opened from the KDS)
+func handleOrderEditPanel(w http.ResponseWriter, r *http.Request) {
+	o, ok := getOrder(r.FormValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	fmt.Fprintf(w, `
+	<div class="panel-overlay" onclick="if(event.target === this) this.remove()">
+		<div class="panel" style="width: min(720px, 100vw);">
+			<div class="panel-header">
+				<h2>✎ Order #%s <small style="color:#888;">%s</small></h2>
+				<div class="icon-btn" onclick="this.closest('.panel-overlay').remove()">✕</div>
+			</div>
+			<div class="panel-body">`, o.Number(), o.Status)
+
+	editable := orderEditable(o.Status)
+	switch o.Status {
+	case StatusCancelled:
+		fmt.Fprint(w, `<p style="color:#e74c3c; font-size:1.3rem;">This order has been cancelled.</p>`)
+	case StatusPickedUp:
+		fmt.Fprint(w, `<p style="color:#888; font-size:1.3rem;">This order has been picked up and can no longer be changed.</p>`)
+	}
+	if due := surchargeDue(o.ID); due > 0.005 && o.Status != StatusCancelled {
+		fmt.Fprintf(w, `
+				<form class="edit-item" style="border-color:#f39c12;" hx-post="/orders/collect" hx-target="closest .panel-overlay" hx-swap="outerHTML">
+					<input type="hidden" name="id" value="%d">
+					<b>💰 RM%.2f still to collect</b>
+					<button class="btn-kds btn-flag">✔ Collected</button>
+				</form>`, o.ID, due)
+	}
+
+	for _, item := range o.Items {
+		if item.Voided {
+			fmt.Fprintf(w, `<div class="edit-item voided"><s>%s</s> <small>VOID</small></div>`, html.EscapeString(item.Name))
+			continue
+		}
+		if !editable {
+			fmt.Fprintf(w, `<div class="edit-item"><b>%s</b> <small>RM%.2f</small> <small>%s</small></div>`,
+				html.EscapeString(item.Name), item.Price, html.EscapeString(strings.Join(item.ModifierNames(), ", ")))
+			continue
+		}
+		options := modifierInputs(item)
+		if options == "" {
+			options = `<small>` + html.EscapeString(strings.Join(item.ModifierNames(), ", ")) + `</small>`
+		}
+		fmt.Fprintf(w, `
+				<form class="edit-item" hx-target="closest .panel-overlay" hx-swap="outerHTML">
+					<input type="hidden" name="id" value="%d">
+					<input type="hidden" name="item" value="%d">
+					<b>%s</b> <small>RM%.2f</small>
+					%s
+					<input type="text" name="remarks" value="%s" placeholder="Remark (e.g. no onions)">
+					<div style="display:flex; gap:8px;">
+						%s
+						<button class="btn-kds btn-flag" hx-post="/orders/amend-item">✎ Amend</button>
+						<button class="btn-kds btn-void" hx-post="/orders/void-item" hx-confirm="Void %s?">🗑 Void</button>
+					</div>
+				</form>`, o.ID, item.ID, html.EscapeString(item.Name), item.Price, options, html.EscapeString(item.Remark()), reasonSelect(), html.EscapeString(item.Name))
+	}
+
+	if editable {
+		fmt.Fprintf(w, `
+				<form class="edit-item" style="border-color:#e74c3c;" hx-post="/orders/cancel" hx-target="closest .panel-overlay" hx-swap="outerHTML" hx-confirm="Cancel the whole order and refund it?">
+					<input type="hidden" name="id" value="%d">
+					<b>Cancel whole order</b>
+					<div style="display:flex; gap:8px;">%s<button class="btn-kds btn-void">✖ Cancel Order</button></div>
+				</form>`, o.ID, reasonSelect())
+	}
+
+	fmt.Fprint(w, `
+			</div>
+		</div>
+	</div>`)
+}
+
+// orderEditable reports whether staff may still amend, void or cancel an order
+func orderEditable(status string) bool {
+	return status != StatusCancelled && status != StatusPickedUp
+}
+
+// modifierInputs renders the options of the item's product type, preset to what was ordered
+// ("" for products without options)
+func modifierInputs(item OrderItem) string {
+	chosen := map[string]bool{}
+	for _, name := range item.ModifierNames() {
+		chosen[name] = true
+	}
+	checked := func(name string) string {
+		if chosen[name] {
+			return " checked"
+		}
+		return ""
+	}
+	addon := func(field, name string) string {
+		return fmt.Sprintf(`<label><input type="checkbox" name="%s"%s> %s (+RM%.2f)</label> `, field, checked(name), name, addonPrices[name])
+	}
+	radio := func(field, name string) string {
+		return fmt.Sprintf(`<label><input type="radio" name="%s" value="%s"%s> %s</label> `, field, name, checked(name), name)
+	}
+
+	var typeTag string
+	db.QueryRow("SELECT COALESCE(type_tag, '') FROM products WHERE id = ?", item.ProductID).Scan(&typeTag)
+	switch typeTag {
+	case "pizza_opt":
+		return `<div>` + addon("extra_cheese", "Extra Cheese") + addon("extra_topping", "Extra Topping") + `</div>`
+	case "pasta_opt":
+		return `<div>` + addon("extra_pasta", "Extra Pasta") + `</div>`
+	case "coffee_opt":
+		return `<div>` + radio("temp", "Ice") + radio("temp", "Hot") + `</div><div>` +
+			radio("sweetness", "Regular") + radio("sweetness", "Less Sweet") + radio("sweetness", "Least Sweet") + `</div>`
+	}
+	return ""
+}
+
+// modifierTotal is the price the line's options added; legacy lines are priced from today's add-on list
+func modifierTotal(item OrderItem) float64 {
+	total := 0.0
+	if len(item.Modifiers) > 0 {
+		for _, m := range item.Modifiers {
+			total += m.Price
+		}
+		return total
+	}
+	for _, name := range item.ModifierNames() {
+		total += addonPrices[name]
+	}
+	return total
+}
+
+// stockLine is the cart form of an order line, as the inventory code sees it
+func stockLine(item OrderItem) CartItem {
+	mods := item.Modifiers
+	if len(mods) == 0 {
+		for _, name := range item.ModifierNames() {
+			mods = append(mods, Modifier{Name: name})
+		}
+	}
+	return CartItem{ProductID: item.ProductID, Name: item.Name, Options: mods}
+}
+
+// restockItems gives the ingredients of voided or cancelled lines back to the inventory
+func restockItems(orderID int, items []OrderItem, note string) {
+	var lines []CartItem
+	for _, item := range items {
+		lines = append(lines, stockLine(item))
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return
+	}
+	defer tx.Rollback()
+	if err := returnStock(tx, orderID, lines, note); err != nil {
+		fmt.Println("Error returning stock:", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Println("Error returning stock:", err)
+	}
+}
+
+// getOrderItem loads one line plus its order, ok=false if it doesn't belong to the order
+func getOrderItem(orderID, itemID int) (OrderItem, bool) {
+	o, ok := getOrder(strconv.Itoa(orderID))
+	if !ok {
+		return OrderItem{}, false
+	}
+	for _, item := range o.Items {
+		if item.ID == itemID {
+			return item, true
+		}
+	}
+	return OrderItem{}, false
+}
+
+// handleAmendOrderItem changes an item's options and remark and flags the ticket as MODIFIED.
+// A cheaper line is refunded the difference; a dearer one adds it to the order total to collect.
+func handleAmendOrderItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	orderID, _ := strconv.Atoi(r.FormValue("id"))
+	itemID, _ := strconv.Atoi(r.FormValue("item"))
+	if orderLocked(orderID) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+	if !orderStillEditable(w, orderID) {
+		return
+	}
+	item, ok := getOrderItem(orderID, itemID)
+	if !ok || item.Voided {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Products without options keep what they had
+	newMods := item.Modifiers
+	if modifierInputs(item) != "" {
+		newMods = selectedModifiers(r)
+	}
+	newRemark := strings.TrimSpace(r.FormValue("remarks"))
+	newPrice := item.Price - modifierTotal(item) + modifierTotal(OrderItem{Modifiers: newMods})
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE order_items SET remarks = ?, options = ?, price = ? WHERE id = ?",
+		newRemark, formatOptions(modifierNames(newMods), newRemark), newPrice, itemID); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM order_item_options WHERE order_item_id = ?", itemID); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := saveModifiers(tx, int64(itemID), newMods); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Swap the old options' ingredients for the new ones
+	oldOptions := stockLine(item)
+	oldOptions.ProductID = 0
+	newOptions := CartItem{Name: item.Name, Options: newMods}
+	if err := returnStock(tx, orderID, []CartItem{oldOptions}, "Amend: "+item.Name); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	short, err := shortOfStock(tx, []CartItem{newOptions})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if len(short) > 0 {
+		http.Error(w, "Not enough stock left to make "+item.Name+" this way", http.StatusConflict)
+		return
+	}
+	if err := depleteStock(tx, int64(orderID), []CartItem{newOptions}); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// A dearer line is owed, not paid: it only counts as takings once collected
+	diff := (newPrice - item.Price) * (1 + taxRate)
+	if diff > 0 {
+		if _, err := tx.Exec("UPDATE orders SET surcharge_due = COALESCE(surcharge_due, 0) + ? WHERE id = ?", diff, orderID); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	db.Exec("UPDATE orders SET kitchen_alert = ? WHERE id = ?", AlertModified, orderID)
+	recordOrderEvent(orderID, "amend", fmt.Sprintf("%s: %s → %s, remark %q → %q (%s)", item.Name,
+		orDefault(strings.Join(item.ModifierNames(), ", "), "no options"), orDefault(strings.Join(modifierNames(newMods), ", "), "no options"),
+		item.Remark(), newRemark, r.FormValue("reason")))
+
+	if diff < 0 {
+		if err := creditOrder(orderID, -diff, "Amend: "+item.Name+" ("+r.FormValue("reason")+")"); err != nil {
+			fmt.Printf("Refund error: %v", err)
+		}
+	} else if diff > 0 {
+		recordOrderEvent(orderID, "surcharge", fmt.Sprintf("RM%.2f to collect for %s", diff, item.Name))
+	}
+
+	handleOrderEditPanel(w, r)
+}
+
+// orderStillEditable refuses changes to cancelled and picked-up orders
+func orderStillEditable(w http.ResponseWriter, orderID int) bool {
+	var status string
+	db.QueryRow("SELECT status FROM orders WHERE id = ?", orderID).Scan(&status)
+	if !orderEditable(status) {
+		http.Error(w, "This order is "+strings.ToLower(status)+" and can no longer be changed", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// handleVoidOrderItem removes one item, refunds it and flags the ticket.
+// Voiding the last remaining item cancels the whole order.
+func handleVoidOrderItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	orderID, _ := strconv.Atoi(r.FormValue("id"))
+	itemID, _ := strconv.Atoi(r.FormValue("item"))
+	reason := r.FormValue("reason")
+	if orderLocked(orderID) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+	if !orderStillEditable(w, orderID) {
+		return
+	}
+	item, ok := getOrderItem(orderID, itemID)
+	if !ok || item.Voided {
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := db.Exec("UPDATE order_items SET voided = 1, void_reason = ? WHERE id = ?", reason, itemID); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	recordOrderEvent(orderID, "void_item", fmt.Sprintf("%s (%s)", item.Name, reason))
+	restockItems(orderID, []OrderItem{item}, "Void: "+item.Name)
+	releaseDailyCaps()
+
+	var remaining int
+	db.QueryRow("SELECT COUNT(*) FROM order_items WHERE order_id = ? AND COALESCE(voided, 0) = 0", orderID).Scan(&remaining)
+	if remaining == 0 {
+		cancelOrder(orderID, reason)
+	} else {
+		db.Exec("UPDATE orders SET kitchen_alert = ? WHERE id = ?", AlertModified, orderID)
+		if err := creditOrder(orderID, item.Price*(1+taxRate), "Void: "+item.Name+" ("+reason+")"); err != nil {
+			fmt.Printf("Refund error: %v", err)
+		}
+	}
+
+	handleOrderEditPanel(w, r)
+}
+
+// handleCancelOrder voids the whole order
+func handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	orderID, _ := strconv.Atoi(r.FormValue("id"))
+	if _, ok := getOrder(strconv.Itoa(orderID)); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if orderLocked(orderID) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+	if !orderStillEditable(w, orderID) {
+		return
+	}
+	cancelOrder(orderID, r.FormValue("reason"))
+	handleOrderEditPanel(w, r)
+}
+
+// cancelOrder marks the order cancelled, refunds whatever hasn't been refunded yet
+// and shows a VOID ticket on the KDS until the kitchen acknowledges it.
+func cancelOrder(orderID int, reason string) {
+	id := strconv.Itoa(orderID)
+	o, ok := getOrder(id)
+	if !ok {
+		return
+	}
+	if err := updateOrderStatus(id, StatusCancelled); err != nil {
+		fmt.Printf("Error cancelling: %v", err)
+		return
+	}
+
+	// Lines voided earlier have already given their stock back
+	var lines []OrderItem
+	for _, item := range o.Items {
+		if !item.Voided {
+			lines = append(lines, item)
+		}
+	}
+	restockItems(orderID, lines, "Cancelled")
+	releaseDailyCaps()
+	db.Exec("UPDATE orders SET kitchen_alert = ? WHERE id = ?", AlertVoid, orderID)
+	recordOrderEvent(orderID, "cancel", reason)
+
+	// Nothing more is owed on a cancelled order; what was paid goes back
+	db.Exec("UPDATE orders SET surcharge_due = 0 WHERE id = ?", orderID)
+	if err := issueRefund(orderID, refundableAmount(orderID), "Cancelled ("+reason+")"); err != nil {
+		fmt.Printf("Refund error: %v", err)
+	}
+}
+
+// surchargeDue is what an amend added to the order that hasn't been collected yet
+func surchargeDue(orderID int) float64 {
+	var due float64
+	db.QueryRow("SELECT COALESCE(surcharge_due, 0) FROM orders WHERE id = ?", orderID).Scan(&due)
+	return due
+}
+
+// creditOrder gives money back for a cheaper amend or a voided line. Whatever is still owed
+// from an earlier amend is written off first; only the rest is refunded.
+func creditOrder(orderID int, amount float64, reason string) error {
+	if owed := math.Min(surchargeDue(orderID), amount); owed > 0 {
+		db.Exec("UPDATE orders SET surcharge_due = surcharge_due - ? WHERE id = ?", owed, orderID)
+		recordOrderEvent(orderID, "surcharge", fmt.Sprintf("RM%.2f no longer owed (%s)", owed, reason))
+		amount -= owed
+	}
+	return issueRefund(orderID, amount, reason)
+}
+
+// handleCollectSurcharge records that the customer paid what an amend added;
+// only then does it count towards the order total and the day's takings
+func handleCollectSurcharge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	orderID, _ := strconv.Atoi(r.FormValue("id"))
+	if orderLocked(orderID) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+	due := surchargeDue(orderID)
+	res, err := db.Exec("UPDATE orders SET total_amount = total_amount + surcharge_due, surcharge_due = 0 WHERE id = ? AND surcharge_due > 0 AND status != ?",
+		orderID, StatusCancelled)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		recordOrderEvent(orderID, "surcharge_collected", fmt.Sprintf("RM%.2f", due))
+	}
+	handleOrderEditPanel(w, r)
+}
+
+// handleKitchenAck clears a MODIFIED/VOID banner once the kitchen has seen it
+func handleKitchenAck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	db.Exec("UPDATE orders SET kitchen_alert = NULL WHERE id = ?", id)
+	recordOrderEvent(id, "kitchen_ack", "")
+	handleGetKitchenOrders(w, r)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// Modifier is one chosen option on an order line, e.g. {"Add-ons", "Extra Cheese", 3}
+type Modifier struct {
+	Group string
+	Name  string
+	Price float64 // Price delta on top of the base price
+}
+
+// modifierNames lists the chosen option names in the order they were picked
+func modifierNames(mods []Modifier) []string {
+	var names []string
+	for _, m := range mods {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// legacyModifier rebuilds a structured option from its name in the old options text.
+// Add-ons are priced at today's list price; the original delta was never stored.
+func legacyModifier(name string) Modifier {
+	switch name {
+	case "Ice", "Hot":
+		return Modifier{"Temperature", name, 0}
+	case "Regular", "Less Sweet", "Least Sweet":
+		return Modifier{"Sweetness", name, 0}
+	}
+	if price, ok := addonPrices[name]; ok {
+		return Modifier{"Add-ons", name, price}
+	}
+	return Modifier{"Options", name, 0}
+}
+
+// backfillOrderItemOptions gives lines placed before options were stored as rows their
+// order_item_options, parsed from the options text, so reorders and reports see them
+func backfillOrderItemOptions(db *sql.DB) {
+	rows, err := db.Query(`SELECT id, options FROM order_items oi
+		WHERE COALESCE(options, '') != '' AND NOT EXISTS (SELECT 1 FROM order_item_options WHERE order_item_id = oi.id)`)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return
+	}
+	legacy := map[int64][]Modifier{}
+	for rows.Next() {
+		var id int64
+		var options string
+		rows.Scan(&id, &options)
+		for _, name := range (OrderItem{Options: options}).ModifierNames() {
+			legacy[id] = append(legacy[id], legacyModifier(name))
+		}
+	}
+	rows.Close()
+	if len(legacy) == 0 {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return
+	}
+	defer tx.Rollback()
+	for id, mods := range legacy {
+		if err := saveModifiers(tx, id, mods); err != nil {
+			fmt.Println("Error backfilling order options:", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Println("Error backfilling order options:", err)
+	}
+}
+
+// formatOptions builds the legacy one-line options text ("Extra Cheese, Ice | RMK: no onions")
+// that is still stored in order_items.options for older screens and exports.
+func formatOptions(names []string, remark string) string {
+	text := strings.Join(names, ", ")
+	if remark != "" {
+		if text != "" {
+			text += " | "
+		}
+		text += "RMK: " + remark
+	}
+	return text
+}
+
+// ModifierNames returns the line's options, falling back to parsing the legacy
+// options text for orders placed before modifiers were stored separately.
+func (i OrderItem) ModifierNames() []string {
+	if len(i.Modifiers) > 0 {
+		return modifierNames(i.Modifiers)
+	}
+	mods := strings.SplitN(i.Options, " | ", 2)[0]
+	if mods == "" || strings.HasPrefix(mods, "RMK: ") {
+		return nil
+	}
+	return strings.Split(mods, ", ")
+}
+
+// Remark returns the customer's free-text note for the line
+func (i OrderItem) Remark() string {
+	if i.Remarks != "" {
+		return i.Remarks
+	}
+	for _, part := range strings.SplitN(i.Options, " | ", 2) {
+		if strings.HasPrefix(part, "RMK: ") {
+			return strings.TrimPrefix(part, "RMK: ")
+		}
+	}
+	return ""
+}
+
+// saveModifiers stores a line's chosen options
+func saveModifiers(tx *sql.Tx, orderItemID int64, mods []Modifier) error {
+	for _, m := range mods {
+		if _, err := tx.Exec("INSERT INTO order_item_options (order_item_id, group_name, option_name, price_delta) VALUES (?, ?, ?, ?)",
+			orderItemID, m.Group, m.Name, m.Price); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadModifiers returns the stored options of one order line
+func loadModifiers(orderItemID int) []Modifier {
+	rows, err := db.Query("SELECT group_name, option_name, price_delta FROM order_item_options WHERE order_item_id = ? ORDER BY id", orderItemID)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var mods []Modifier
+	for rows.Next() {
+		var m Modifier
+		rows.Scan(&m.Group, &m.Name, &m.Price)
+		mods = append(mods, m)
+	}
+	return mods
+}
+
+// handleReorder rebuilds the cart from a previous order at today's prices
+func handleReorder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	o, ok := getOrder(r.FormValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	for _, item := range o.Items {
+		if item.Voided {
+			continue
+		}
+		// Older lines only have the product name to go on
+		var p Product
+		err := db.QueryRow("SELECT id, name, price, in_stock FROM products WHERE id = ? OR (? = 0 AND name = ?) LIMIT 1",
+			item.ProductID, item.ProductID, item.Name).Scan(&p.ID, &p.Name, &p.Price, &p.InStock)
+		if err != nil || !p.InStock || !productScheduledNow(p.ID) {
+			continue
+		}
+		// Add-ons are charged at today's price too
+		var mods []Modifier
+		for _, m := range item.Modifiers {
+			m.Price = addonPrices[m.Name]
+			mods = append(mods, m)
+		}
+		cart = append(cart, CartItem{ProductID: p.ID, Name: p.Name, BasePrice: p.Price, Options: mods, Remarks: item.Remark()})
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// ticketOptionsHTML renders a line's options for the KDS in the kitchen language, with the remark highlighted
+func ticketOptionsHTML(item OrderItem, tr translator) string {
+	opts := ""
+	if mods := item.ModifierNames(); len(mods) > 0 {
+		opts = fmt.Sprintf(`<span class="ticket-opt">+ %s</span>`, html.EscapeString(strings.Join(tr.Modifiers(mods), ", ")))
+	}
+	if remark := item.Remark(); remark != "" {
+		opts += fmt.Sprintf(`<span class="ticket-remark">📝 %s</span>`, html.EscapeString(remark))
+	}
+	return opts
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// Customer-facing wording for each order status
+var statusLabels = map[string]string{
+	StatusPaid:      "👨‍🍳 Preparing",
+	StatusCompleted: "✅ Ready for pickup!",
+	StatusPickedUp:  "🍕 Collected — enjoy!",
+	StatusCancelled: "❌ Cancelled (refunded)",
+}
+
+// getOrder loads a single order with its items, ok=false when it doesn't exist
+func getOrder(id string) (Order, bool) {
+	orders := getOrdersByQuery(`SELECT `+orderColumns+` FROM orders WHERE id = ?`, id)
+	if len(orders) == 0 {
+		return Order{}, false
+	}
+	return orders[0], true
+}
+
+// handleSuccess shows the pickup number right after payment
+func handleSuccess(w http.ResponseWriter, r *http.Request) {
+	cart = []CartItem{}
+
+	id := r.URL.Query().Get("order")
+	number := ""
+	eta := "Pickup in ~20 mins."
+	if o, ok := getOrder(id); ok {
+		number = fmt.Sprintf(`
+			<p class="text-sm text-gray-500 uppercase tracking-wider">Your pickup number</p>
+			<div class="text-6xl font-black text-orange-600 my-2">%s</div>
+			<a href="/order?id=%d" class="block text-sm text-orange-600 underline mb-6">Track your order</a>`, o.Number(), o.ID)
+		if t := parseDBTime(o.EstimatedAt); !t.IsZero() {
+			eta = fmt.Sprintf("Estimated ready around %s.", t.Local().Format("3:04 pm"))
+		}
+	}
+
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html lang="en">
+		<head>
+			<meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0">
+			<script src="https://cdn.tailwindcss.com"></script>
+		</head>
+		<body class="bg-gray-50 flex items-center justify-center h-screen">
+			<div class="bg-white p-8 rounded-xl shadow-lg text-center max-w-md">
+				<div class="text-6xl mb-4">🎉</div>
+				<h1 class="text-2xl font-bold text-gray-800 mb-2">Payment Successful!</h1>
+				%s
+				<p class="text-gray-600 mb-6">Your order has been sent to the kitchen. %s</p>
+				<a href='/' class="inline-block bg-orange-600 text-white px-6 py-2 rounded-lg font-medium hover:bg-orange-700 transition">Order More</a>
+			</div>
+		</body>
+		</html>
+	`, number, eta)
+}
+
+// handleOrderStatusPage is the customer's live tracking page (doubles as a receipt)
+func handleOrderStatusPage(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if _, ok := getOrder(id); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html lang="en">
+		<head>
+			<meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0">
+			<title>Order Status</title>
+			<script src="https://cdn.tailwindcss.com"></script>
+			<script src="https://unpkg.com/htmx.org@1.9.10"></script>
+		</head>
+		<body class="bg-gray-50 flex items-center justify-center min-h-screen p-4">
+			<div class="bg-white p-8 rounded-xl shadow-lg max-w-md w-full"
+				hx-get="/order/status?id=%s" hx-trigger="load, every 5s">
+			</div>
+		</body>
+		</html>
+	`, id)
+}
+
+// handleOrderStatus renders the polled body of the tracking page
+func handleOrderStatus(w http.ResponseWriter, r *http.Request) {
+	o, ok := getOrder(r.URL.Query().Get("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	label := statusLabels[o.Status]
+	if label == "" {
+		label = o.Status
+	}
+
+	eta := ""
+	if t := parseDBTime(o.EstimatedAt); o.Status == StatusPaid && !t.IsZero() {
+		eta = fmt.Sprintf(`<div class="text-sm text-gray-500 mt-1">Estimated ready around %s</div>`, t.Local().Format("3:04 pm"))
+	}
+
+	fmt.Fprintf(w, `
+		<div class="text-center border-b border-dashed border-gray-200 pb-4 mb-4">
+			<p class="text-sm text-gray-500 uppercase tracking-wider">Pickup number</p>
+			<div class="text-6xl font-black text-orange-600 my-2">%s</div>
+			<div class="text-xl font-bold text-gray-800">%s</div>
+			%s
+		</div>
+		<ul class="divide-y divide-gray-100 text-sm">`, o.Number(), label, eta)
+
+	for _, item := range o.Items {
+		opts := ""
+		if mods := item.ModifierNames(); len(mods) > 0 {
+			opts = fmt.Sprintf(`<div class="text-xs text-gray-500">%s</div>`, html.EscapeString(strings.Join(mods, ", ")))
+		}
+		if remark := item.Remark(); remark != "" {
+			opts += fmt.Sprintf(`<div class="text-xs text-orange-600 italic">Note: %s</div>`, html.EscapeString(remark))
+		}
+		if item.Voided {
+			fmt.Fprintf(w, `<li class="py-2 flex justify-between text-gray-400 line-through"><div>%s</div><span>RM%.2f</span></li>`, html.EscapeString(item.Name), item.Price)
+			continue
+		}
+		fmt.Fprintf(w, `<li class="py-2 flex justify-between"><div>%s%s</div><span>RM%.2f</span></li>`, html.EscapeString(item.Name), opts, item.Price)
+	}
+
+	fmt.Fprintf(w, `
+		</ul>
+		<div class="flex justify-between font-bold text-gray-900 border-t border-gray-200 pt-2 mt-2">
+			<span>Total (incl. tax)</span><span>RM%.2f</span>
+		</div>
+		<form method="post" action="/order/reorder" class="text-center mt-4">
+			<input type="hidden" name="id" value="%d">
+			<button class="text-sm font-medium text-orange-600 hover:underline">🔁 Order this again</button>
+		</form>
+		<p class="text-xs text-gray-400 text-center mt-4">Order ref #%d · %s</p>`, o.Total, o.ID, o.ID, o.CreatedAt)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Pickup number configuration
+const (
+	defaultDayStartHour = 4    // Sequence resets at 04:00, after the late-night shift closes
+	usePickupPrefixes   = true // Prefix numbers by channel (W-12, K-5)
+	defaultChannel      = "web"
+)
+
+const dayStartHourKey = "business_day_start_hour"
+
+// Ordering channels and their pickup number prefix
+var channelPrefixes = map[string]string{
+	"web":   "W",
+	"kiosk": "K",
+}
+
+// businessDayStartHour is the hour the business day rolls over, set on the close day page
+func businessDayStartHour() int {
+	hour, err := strconv.Atoi(getSetting(dayStartHourKey, ""))
+	if err != nil || hour < 0 || hour > 23 {
+		return defaultDayStartHour
+	}
+	return hour
+}
+
+// businessDay returns the business day (YYYY-MM-DD) a moment belongs to.
+// Orders placed before businessDayStartHour count towards the previous day.
+func businessDay(t time.Time) string {
+	return t.Local().Add(-time.Duration(businessDayStartHour()) * time.Hour).Format("2006-01-02")
+}
+
+// nextPickupNumber atomically increments the channel's sequence for the business day
+// as part of the checkout transaction.
+func nextPickupNumber(tx *sql.Tx, day, channel string) (string, error) {
+	var n int
+	err := tx.QueryRow(`
+		INSERT INTO pickup_sequences (business_day, channel, last_number) VALUES (?, ?, 1)
+		ON CONFLICT(business_day, channel) DO UPDATE SET last_number = last_number + 1
+		RETURNING last_number`, day, channel).Scan(&n)
+	if err != nil {
+		return "", err
+	}
+
+	if prefix := channelPrefixes[channel]; usePickupPrefixes && prefix != "" {
+		return fmt.Sprintf("%s-%d", prefix, n), nil
+	}
+	return fmt.Sprintf("%d", n), nil
+}
+
+// requestChannel works out which channel an order came from.
+// Kiosks open the menu with ?channel=kiosk once, which is remembered in a cookie.
+func requestChannel(r *http.Request) string {
+	if c, err := r.Cookie("channel"); err == nil {
+		if _, ok := channelPrefixes[c.Value]; ok {
+			return c.Value
+		}
+	}
+	return defaultChannel
+}
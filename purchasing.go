@@ -0,0 +1,681 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Purchase order lifecycle
+const (
+	POStatusDraft    = "Draft"
+	POStatusSent     = "Sent"
+	POStatusReceived = "Received"
+)
+
+type Supplier struct {
+	ID      int
+	Name    string
+	Contact string
+	Phone   string
+	Email   string
+}
+
+type PurchaseOrder struct {
+	ID         int
+	SupplierID int
+	Supplier   Supplier
+	Status     string
+	CreatedAt  string
+	SentAt     string
+	ReceivedAt string
+	Lines      []POLine
+}
+
+type POLine struct {
+	ID           int
+	IngredientID int
+	Ingredient   string
+	Unit         string
+	Quantity     float64
+	UnitCost     float64
+	ReceivedQty  float64
+	ReceivedCost float64
+}
+
+// Total is the expected cost, or the invoiced cost once received
+func (po PurchaseOrder) Total() float64 {
+	total := 0.0
+	for _, l := range po.Lines {
+		if po.Status == POStatusReceived {
+			total += l.ReceivedQty * l.ReceivedCost
+		} else {
+			total += l.Quantity * l.UnitCost
+		}
+	}
+	return total
+}
+
+func getSuppliers() []Supplier {
+	rows, err := db.Query("SELECT id, name, COALESCE(contact, ''), COALESCE(phone, ''), COALESCE(email, '') FROM suppliers ORDER BY name")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var out []Supplier
+	for rows.Next() {
+		var s Supplier
+		rows.Scan(&s.ID, &s.Name, &s.Contact, &s.Phone, &s.Email)
+		out = append(out, s)
+	}
+	return out
+}
+
+func getPurchaseOrder(id int) (PurchaseOrder, bool) {
+	var po PurchaseOrder
+	err := db.QueryRow(`
+		SELECT po.id, COALESCE(po.supplier_id, 0), po.status, po.created_at, COALESCE(po.sent_at, ''), COALESCE(po.received_at, ''),
+		       COALESCE(s.name, ''), COALESCE(s.contact, ''), COALESCE(s.phone, ''), COALESCE(s.email, '')
+		FROM purchase_orders po LEFT JOIN suppliers s ON s.id = po.supplier_id
+		WHERE po.id = ?`, id).Scan(&po.ID, &po.SupplierID, &po.Status, &po.CreatedAt, &po.SentAt, &po.ReceivedAt,
+		&po.Supplier.Name, &po.Supplier.Contact, &po.Supplier.Phone, &po.Supplier.Email)
+	if err != nil {
+		return po, false
+	}
+	po.Supplier.ID = po.SupplierID
+
+	rows, err := db.Query(`
+		SELECT l.id, l.ingredient_id, i.name, COALESCE(i.unit, ''), l.quantity, COALESCE(l.unit_cost, 0),
+		       COALESCE(l.received_qty, 0), COALESCE(l.received_cost, 0)
+		FROM purchase_order_items l JOIN ingredients i ON i.id = l.ingredient_id
+		WHERE l.po_id = ? ORDER BY i.name`, id)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return po, true
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l POLine
+		rows.Scan(&l.ID, &l.IngredientID, &l.Ingredient, &l.Unit, &l.Quantity, &l.UnitCost, &l.ReceivedQty, &l.ReceivedCost)
+		po.Lines = append(po.Lines, l)
+	}
+	return po, true
+}
+
+type reorderLine struct {
+	Ingredient
+	Par       float64
+	Suggested float64
+}
+
+// suggestedReorders lists ingredients below par, keyed by supplier ID (0 = no supplier assigned).
+// The suggestion tops stock back up to par, less anything already on an open PO.
+func suggestedReorders() map[int][]reorderLine {
+	rows, err := db.Query(`
+		SELECT i.id, i.name, COALESCE(i.unit, ''), i.on_hand, COALESCE(i.cost_per_unit, 0), i.par_level, COALESCE(i.supplier_id, 0),
+		       COALESCE((SELECT SUM(l.quantity) FROM purchase_order_items l JOIN purchase_orders po ON po.id = l.po_id
+		                 WHERE l.ingredient_id = i.id AND po.status != 'Received'), 0)
+		FROM ingredients i
+		WHERE COALESCE(i.par_level, 0) > 0 AND i.on_hand < i.par_level
+		ORDER BY i.name`)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	out := map[int][]reorderLine{}
+	for rows.Next() {
+		var l reorderLine
+		var supplierID int
+		var onOrder float64
+		rows.Scan(&l.ID, &l.Name, &l.Unit, &l.OnHand, &l.Cost, &l.Par, &supplierID, &onOrder)
+		l.Suggested = l.Par - l.OnHand - onOrder
+		if l.Suggested > 0 {
+			out[supplierID] = append(out[supplierID], l)
+		}
+	}
+	return out
+}
+
+func redirectToPurchasing(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, "/admin/purchasing?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+func redirectToPO(w http.ResponseWriter, r *http.Request, id int, msg string) {
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchasing/po?id=%d&msg=%s", id, url.QueryEscape(msg)), http.StatusSeeOther)
+}
+
+// handleAdminPurchasing shows suggested reorders, purchase orders, par levels and suppliers
+func handleAdminPurchasing(w http.ResponseWriter, r *http.Request) {
+	suppliers := getSuppliers()
+	supplierNames := map[int]string{0: "No supplier assigned"}
+	for _, s := range suppliers {
+		supplierNames[s.ID] = s.Name
+	}
+
+	adminPageStart(w, "Purchasing")
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		fmt.Fprintf(w, `<div class="bg-green-50 border border-green-200 text-green-800 rounded p-3">%s</div>`, html.EscapeString(msg))
+	}
+
+	// Suggested reorders, one block per supplier
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">🛒 Below par</h2>`)
+	suggestions := suggestedReorders()
+	if len(suggestions) == 0 {
+		fmt.Fprint(w, `<p class="text-gray-400 text-sm">Everything is at or above par (or already on order).</p>`)
+	}
+	for _, s := range append([]Supplier{{ID: 0}}, suppliers...) {
+		lines, ok := suggestions[s.ID]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, `
+        <div class="border rounded p-4 mb-4">
+            <div class="flex justify-between items-center mb-2"><h3 class="font-semibold">%s</h3>`, html.EscapeString(supplierNames[s.ID]))
+		if s.ID > 0 {
+			fmt.Fprintf(w, `
+                <form method="post" action="/admin/purchasing/suggest"><input type="hidden" name="supplier_id" value="%d">
+                    <button class="bg-orange-600 text-white rounded px-3 py-1 text-sm font-semibold">Create purchase order</button></form>`, s.ID)
+		} else {
+			fmt.Fprint(w, `<span class="text-xs text-gray-500">Assign a supplier below to order these</span>`)
+		}
+		fmt.Fprint(w, `</div>
+            <table class="w-full text-sm"><thead class="text-left text-gray-500"><tr><th>Ingredient</th><th>On hand</th><th>Par</th><th>Suggested</th><th>Est. cost</th></tr></thead><tbody>`)
+		for _, l := range lines {
+			fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>%s %s</td><td>%s</td><td class="font-semibold">%s</td><td>RM%.2f</td></tr>`,
+				html.EscapeString(l.Name), formatQty(l.OnHand), html.EscapeString(l.Unit), formatQty(l.Par), formatQty(l.Suggested), l.Suggested*l.Cost)
+		}
+		fmt.Fprint(w, `</tbody></table></div>`)
+	}
+	fmt.Fprint(w, `</section>`)
+
+	// Purchase orders
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">📄 Purchase orders</h2>
+        <table class="w-full text-sm"><thead class="text-left text-gray-500"><tr><th>PO</th><th>Supplier</th><th>Created</th><th>Status</th><th></th></tr></thead><tbody>`)
+	rows, err := db.Query(`SELECT po.id, COALESCE(s.name, ''), po.created_at, po.status
+		FROM purchase_orders po LEFT JOIN suppliers s ON s.id = po.supplier_id ORDER BY po.id DESC LIMIT 50`)
+	count := 0
+	if err == nil {
+		for rows.Next() {
+			var id int
+			var supplier, createdAt, status string
+			rows.Scan(&id, &supplier, &createdAt, &status)
+			count++
+			fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 font-semibold">PO-%04d</td><td>%s</td><td>%s</td><td>%s</td><td class="text-right"><a href="/admin/purchasing/po?id=%d" class="text-blue-600 hover:underline">Open</a></td></tr>`,
+				id, html.EscapeString(supplier), parseDBTime(createdAt).Local().Format("Jan 2, 3:04 pm"), status, id)
+		}
+		rows.Close()
+	}
+	if count == 0 {
+		fmt.Fprint(w, `<tr><td colspan="5" class="py-6 text-center text-gray-400">No purchase orders yet</td></tr>`)
+	}
+	fmt.Fprint(w, `</tbody></table></section>`)
+
+	// Par levels and preferred supplier per ingredient
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">📏 Par levels</h2>
+        <table class="w-full text-sm"><thead class="text-left text-gray-500"><tr><th>Ingredient</th><th>On hand</th><th>Supplier and par level</th></tr></thead><tbody>`)
+	rows, err = db.Query("SELECT id, name, COALESCE(unit, ''), on_hand, COALESCE(par_level, 0), COALESCE(supplier_id, 0) FROM ingredients ORDER BY name")
+	if err == nil {
+		for rows.Next() {
+			var i Ingredient
+			var par float64
+			var supplierID int
+			rows.Scan(&i.ID, &i.Name, &i.Unit, &i.OnHand, &par, &supplierID)
+			options := `<option value="0">— none —</option>`
+			for _, s := range suppliers {
+				selected := ""
+				if s.ID == supplierID {
+					selected = "selected"
+				}
+				options += fmt.Sprintf(`<option value="%d" %s>%s</option>`, s.ID, selected, html.EscapeString(s.Name))
+			}
+			fmt.Fprintf(w, `
+            <tr class="border-t">
+                <td class="py-2 font-semibold">%s</td><td>%s %s</td>
+                <td><form method="post" action="/admin/purchasing/ingredient" class="flex gap-2">
+                    <input type="hidden" name="id" value="%d">
+                    <select name="supplier_id" class="border rounded px-2 py-1">%s</select>
+                    <input type="number" name="par_level" step="any" min="0" value="%s" class="border rounded px-2 py-1 w-28">
+                    <button class="bg-gray-900 text-white rounded px-3 py-1 font-semibold">Save</button>
+                </form></td>
+            </tr>`, html.EscapeString(i.Name), formatQty(i.OnHand), html.EscapeString(i.Unit), i.ID, options, formatQty(par))
+		}
+		rows.Close()
+	}
+	fmt.Fprint(w, `</tbody></table></section>`)
+
+	// Suppliers
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">🚚 Suppliers</h2>
+        <table class="w-full text-sm"><tbody>`)
+	for _, s := range suppliers {
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 font-semibold">%s</td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+			html.EscapeString(s.Name), html.EscapeString(s.Contact), html.EscapeString(s.Phone), html.EscapeString(s.Email))
+	}
+	if len(suppliers) == 0 {
+		fmt.Fprint(w, `<tr><td class="py-6 text-center text-gray-400">No suppliers yet</td></tr>`)
+	}
+	fmt.Fprint(w, `</tbody></table>
+        <form method="post" action="/admin/purchasing/supplier" class="flex gap-2 mt-4 text-sm">
+            <input type="text" name="name" placeholder="Supplier name" required class="border rounded px-2 py-1">
+            <input type="text" name="contact" placeholder="Contact person" class="border rounded px-2 py-1">
+            <input type="tel" name="phone" placeholder="Phone" class="border rounded px-2 py-1 w-36">
+            <input type="email" name="email" placeholder="Email" class="border rounded px-2 py-1">
+            <button class="bg-orange-600 text-white rounded px-3 py-1 font-semibold">+ Add supplier</button>
+        </form>
+    </section>`)
+	adminPageEnd(w)
+}
+
+func handleAdminSupplierCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	_, err := db.Exec("INSERT INTO suppliers (name, contact, phone, email) VALUES (?, ?, ?, ?)",
+		name, strings.TrimSpace(r.FormValue("contact")), strings.TrimSpace(r.FormValue("phone")), strings.TrimSpace(r.FormValue("email")))
+	if err != nil {
+		http.Error(w, "Could not add supplier: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	redirectToPurchasing(w, r, "Added "+name)
+}
+
+// handleAdminIngredientSupply sets an ingredient's preferred supplier and par level
+func handleAdminIngredientSupply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	par, _ := strconv.ParseFloat(r.FormValue("par_level"), 64)
+	var supplierID interface{}
+	if id, _ := strconv.Atoi(r.FormValue("supplier_id")); id > 0 {
+		supplierID = id
+	}
+	if _, err := db.Exec("UPDATE ingredients SET supplier_id = ?, par_level = ? WHERE id = ?", supplierID, par, r.FormValue("id")); err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	redirectToPurchasing(w, r, "Par level saved")
+}
+
+// handleAdminPOSuggest turns a supplier's below-par ingredients into a draft purchase order
+func handleAdminPOSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	supplierID, _ := strconv.Atoi(r.FormValue("supplier_id"))
+	lines := suggestedReorders()[supplierID]
+	if supplierID == 0 || len(lines) == 0 {
+		redirectToPurchasing(w, r, "Nothing to order from this supplier")
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT INTO purchase_orders (supplier_id, status) VALUES (?, ?)", supplierID, POStatusDraft)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	poID, _ := res.LastInsertId()
+	for _, l := range lines {
+		if _, err = tx.Exec("INSERT INTO purchase_order_items (po_id, ingredient_id, quantity, unit_cost) VALUES (?, ?, ?, ?)",
+			poID, l.ID, l.Suggested, l.Cost); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	redirectToPO(w, r, int(poID), "Draft purchase order created")
+}
+
+// handleAdminPODetail shows a purchase order: draft editing, sending, and the receiving form
+func handleAdminPODetail(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	po, ok := getPurchaseOrder(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	adminPageStart(w, fmt.Sprintf("PO-%04d", po.ID))
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		fmt.Fprintf(w, `<div class="bg-green-50 border border-green-200 text-green-800 rounded p-3">%s</div>`, html.EscapeString(msg))
+	}
+	fmt.Fprintf(w, `
+    <section class="bg-white rounded-lg shadow p-6 flex flex-wrap justify-between gap-4">
+        <div>
+            <a href="/admin/purchasing" class="text-sm text-blue-600 hover:underline">← Purchasing</a>
+            <h2 class="font-bold text-lg">PO-%04d · %s</h2>
+            <p class="text-sm text-gray-500">%s %s %s</p>
+        </div>
+        <div class="text-right">
+            <div class="text-sm font-semibold">%s</div>
+            <div class="text-xl font-bold">RM%.2f</div>
+            <div class="flex gap-3 text-sm mt-2">
+                <a href="/admin/purchasing/po/print?id=%d" target="_blank" class="text-blue-600 hover:underline">🖨 Print</a>
+                <a href="/admin/purchasing/po/export?id=%d" class="text-blue-600 hover:underline">⬇ CSV</a>
+            </div>
+        </div>
+    </section>`, po.ID, html.EscapeString(po.Supplier.Name),
+		html.EscapeString(po.Supplier.Contact), html.EscapeString(po.Supplier.Phone), html.EscapeString(po.Supplier.Email),
+		po.Status, po.Total(), po.ID, po.ID)
+
+	switch po.Status {
+	case POStatusReceived:
+		fmt.Fprintf(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">Received %s</h2>
+        <table class="w-full text-sm"><thead class="text-left text-gray-500"><tr><th>Ingredient</th><th>Ordered</th><th>Received</th><th>Expected cost</th><th>Invoiced cost</th></tr></thead><tbody>`,
+			parseDBTime(po.ReceivedAt).Local().Format("Jan 2, 3:04 pm"))
+		for _, l := range po.Lines {
+			costClass := ""
+			if l.ReceivedCost > l.UnitCost {
+				costClass = "text-red-600 font-semibold"
+			}
+			fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>%s %s</td><td>%s</td><td>RM%s</td><td class="%s">RM%s</td></tr>`,
+				html.EscapeString(l.Ingredient), formatQty(l.Quantity), html.EscapeString(l.Unit), formatQty(l.ReceivedQty), formatUnitCost(l.UnitCost), costClass, formatUnitCost(l.ReceivedCost))
+		}
+		fmt.Fprint(w, `</tbody></table></section>`)
+
+	default:
+		// Lines, editable until the order is sent
+		fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">Lines</h2>
+        <table class="w-full text-sm"><thead class="text-left text-gray-500"><tr><th>Ingredient</th><th>Quantity</th><th>Unit cost</th><th>Line total</th><th></th></tr></thead><tbody>`)
+		for _, l := range po.Lines {
+			remove := ""
+			if po.Status == POStatusDraft {
+				remove = fmt.Sprintf(`<form method="post" action="/admin/purchasing/po/line/delete"><input type="hidden" name="po_id" value="%d"><input type="hidden" name="id" value="%d"><button class="text-red-600 hover:underline">Remove</button></form>`, po.ID, l.ID)
+			}
+			fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>%s %s</td><td>RM%s</td><td>RM%.2f</td><td class="text-right">%s</td></tr>`,
+				html.EscapeString(l.Ingredient), formatQty(l.Quantity), html.EscapeString(l.Unit), formatUnitCost(l.UnitCost), l.Quantity*l.UnitCost, remove)
+		}
+		fmt.Fprint(w, `</tbody></table>`)
+		if po.Status == POStatusDraft {
+			fmt.Fprintf(w, `
+        <form method="post" action="/admin/purchasing/po/line" class="flex gap-2 mt-4 text-sm">
+            <input type="hidden" name="po_id" value="%d">
+            <select name="ingredient_id" required class="border rounded px-2 py-1">`, po.ID)
+			for _, i := range getIngredients() {
+				fmt.Fprintf(w, `<option value="%d">%s (%s)</option>`, i.ID, html.EscapeString(i.Name), html.EscapeString(i.Unit))
+			}
+			fmt.Fprintf(w, `</select>
+            <input type="number" name="quantity" step="any" min="0" placeholder="Quantity" required class="border rounded px-2 py-1 w-28">
+            <button class="bg-orange-600 text-white rounded px-3 py-1 font-semibold">+ Add line</button>
+        </form>
+        <form method="post" action="/admin/purchasing/po/send" class="mt-4">
+            <input type="hidden" name="id" value="%d">
+            <button class="bg-gray-900 text-white rounded px-4 py-1.5 font-semibold">📨 Mark as sent to supplier</button>
+        </form>`, po.ID)
+		}
+		fmt.Fprint(w, `</section>`)
+
+		// Receiving: what actually arrived and what the invoice says it cost
+		fmt.Fprintf(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">📥 Receive goods</h2>
+        <form method="post" action="/admin/purchasing/receive" onsubmit="return confirm('Receive this order into stock?')">
+            <input type="hidden" name="id" value="%d">
+            <table class="w-full text-sm"><thead class="text-left text-gray-500"><tr><th>Ingredient</th><th>Ordered</th><th>Received</th><th>Actual unit cost (RM)</th></tr></thead><tbody>`, po.ID)
+		for _, l := range po.Lines {
+			fmt.Fprintf(w, `
+                <tr class="border-t"><td class="py-1">%s</td><td>%s %s</td>
+                    <td><input type="number" name="received_qty_%d" step="any" min="0" value="%s" class="border rounded px-2 py-1 w-28"></td>
+                    <td><input type="number" name="received_cost_%d" step="any" min="0" value="%s" class="border rounded px-2 py-1 w-28"></td></tr>`,
+				html.EscapeString(l.Ingredient), formatQty(l.Quantity), html.EscapeString(l.Unit), l.ID, formatQty(l.Quantity), l.ID, formatQty(l.UnitCost))
+		}
+		fmt.Fprint(w, `</tbody></table>
+            <button class="mt-4 bg-green-600 text-white rounded px-4 py-1.5 font-semibold">Receive into stock</button>
+        </form>
+    </section>`)
+	}
+	adminPageEnd(w)
+}
+
+// draftPO loads a purchase order for editing; only drafts can change
+func draftPO(w http.ResponseWriter, r *http.Request, id int) bool {
+	po, ok := getPurchaseOrder(id)
+	if !ok {
+		http.NotFound(w, r)
+		return false
+	}
+	if po.Status != POStatusDraft {
+		http.Error(w, "This purchase order has already been sent", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func handleAdminPOLineAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	poID, _ := strconv.Atoi(r.FormValue("po_id"))
+	if !draftPO(w, r, poID) {
+		return
+	}
+	qty, _ := strconv.ParseFloat(r.FormValue("quantity"), 64)
+	_, err := db.Exec(`INSERT INTO purchase_order_items (po_id, ingredient_id, quantity, unit_cost)
+		SELECT ?, id, ?, COALESCE(cost_per_unit, 0) FROM ingredients WHERE id = ?`, poID, qty, r.FormValue("ingredient_id"))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	redirectToPO(w, r, poID, "Line added")
+}
+
+func handleAdminPOLineDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	poID, _ := strconv.Atoi(r.FormValue("po_id"))
+	if !draftPO(w, r, poID) {
+		return
+	}
+	db.Exec("DELETE FROM purchase_order_items WHERE id = ? AND po_id = ?", r.FormValue("id"), poID)
+	redirectToPO(w, r, poID, "Line removed")
+}
+
+func handleAdminPOSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	if !draftPO(w, r, id) {
+		return
+	}
+	db.Exec("UPDATE purchase_orders SET status = ?, sent_at = CURRENT_TIMESTAMP WHERE id = ?", POStatusSent, id)
+	redirectToPO(w, r, id, "Marked as sent")
+}
+
+// handleAdminPOReceive books a delivery into stock and takes the invoiced unit costs as the new ingredient costs
+func handleAdminPOReceive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	po, ok := getPurchaseOrder(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// What actually arrived, at what it actually cost
+	qtys := map[int]float64{}
+	costs := map[int]float64{}
+	anyReceived := false
+	for _, l := range po.Lines {
+		qty := 0.0
+		if raw := r.FormValue(fmt.Sprintf("received_qty_%d", l.ID)); raw != "" {
+			var err error
+			if qty, err = strconv.ParseFloat(raw, 64); err != nil || qty < 0 {
+				http.Error(w, "Received quantities must be zero or more", http.StatusBadRequest)
+				return
+			}
+		}
+		cost, err := strconv.ParseFloat(r.FormValue(fmt.Sprintf("received_cost_%d", l.ID)), 64)
+		if err != nil || cost < 0 {
+			cost = l.UnitCost
+		}
+		qtys[l.ID], costs[l.ID] = qty, cost
+		anyReceived = anyReceived || qty > 0
+	}
+	if !anyReceived {
+		http.Error(w, "Enter the quantity received for at least one line", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Claim the PO first so a double submit can't receive it twice
+	res, err := tx.Exec("UPDATE purchase_orders SET status = ?, received_at = CURRENT_TIMESTAMP WHERE id = ? AND status != ?",
+		POStatusReceived, id, POStatusReceived)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "This purchase order has already been received", http.StatusConflict)
+		return
+	}
+
+	note := fmt.Sprintf("PO-%04d", po.ID)
+	for _, l := range po.Lines {
+		qty, cost := qtys[l.ID], costs[l.ID]
+		_, err = tx.Exec("UPDATE purchase_order_items SET received_qty = ?, received_cost = ? WHERE id = ?", qty, cost, l.ID)
+		if err == nil && qty > 0 {
+			// Unit cost becomes the weighted average of the stock on hand and this delivery
+			_, err = tx.Exec(`UPDATE ingredients SET
+				cost_per_unit = CASE WHEN on_hand > 0 THEN (on_hand * COALESCE(cost_per_unit, 0) + ? * ?) / (on_hand + ?) ELSE ? END,
+				on_hand = on_hand + ?
+				WHERE id = ?`, qty, cost, qty, cost, qty, l.IngredientID)
+			if err == nil {
+				_, err = tx.Exec("INSERT INTO stock_movements (ingredient_id, change, reason, note) VALUES (?, ?, 'delivery', ?)", l.IngredientID, qty, note)
+			}
+		}
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := refreshStockAvailability(tx); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	redirectToPO(w, r, id, "Received into stock")
+}
+
+// handleAdminPOPrint renders the purchase order as a document to send to the supplier
+func handleAdminPOPrint(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	po, ok := getPurchaseOrder(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>PO-%04d</title>
+    <style>
+        body { font-family: sans-serif; max-width: 180mm; margin: 10mm auto; font-size: 11pt; }
+        h1 { margin: 0 0 4mm; }
+        table { width: 100%%; border-collapse: collapse; margin-top: 6mm; }
+        th, td { border-bottom: 1px solid #ccc; padding: 4px; text-align: left; }
+        td.num, th.num { text-align: right; }
+    </style>
+</head>
+<body onload="window.print()">
+    <h1>Purchase Order PO-%04d</h1>
+    <p>Date: %s<br>To: <strong>%s</strong><br>%s %s %s</p>
+    <table>
+        <tr><th>Item</th><th>Unit</th><th class="num">Quantity</th><th class="num">Unit price (RM)</th><th class="num">Amount (RM)</th></tr>`,
+		po.ID, po.ID, parseDBTime(po.CreatedAt).Local().Format("Jan 2 2006"), html.EscapeString(po.Supplier.Name),
+		html.EscapeString(po.Supplier.Contact), html.EscapeString(po.Supplier.Phone), html.EscapeString(po.Supplier.Email))
+	for _, l := range po.Lines {
+		fmt.Fprintf(w, `
+        <tr><td>%s</td><td>%s</td><td class="num">%s</td><td class="num">%s</td><td class="num">%.2f</td></tr>`,
+			html.EscapeString(l.Ingredient), html.EscapeString(l.Unit), formatQty(l.Quantity), formatUnitCost(l.UnitCost), l.Quantity*l.UnitCost)
+	}
+	fmt.Fprintf(w, `
+        <tr><th colspan="4" class="num">Total</th><th class="num">%.2f</th></tr>
+    </table>
+</body>
+</html>`, po.Total())
+}
+
+func handleAdminPOExport(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	po, ok := getPurchaseOrder(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="PO-%04d.csv"`, po.ID))
+	out := csv.NewWriter(w)
+	out.Write([]string{"po", "supplier", "status", "ingredient", "unit", "quantity", "unit_cost", "received_qty", "received_cost"})
+	for _, l := range po.Lines {
+		out.Write([]string{
+			fmt.Sprintf("PO-%04d", po.ID), po.Supplier.Name, po.Status, l.Ingredient, l.Unit,
+			strconv.FormatFloat(l.Quantity, 'f', -1, 64), strconv.FormatFloat(l.UnitCost, 'f', -1, 64),
+			strconv.FormatFloat(l.ReceivedQty, 'f', -1, 64), strconv.FormatFloat(l.ReceivedCost, 'f', -1, 64),
+		})
+	}
+	out.Flush()
+}
+
+// formatQty prints a quantity in full, without exponents or rounding
+func formatQty(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatUnitCost prints a price to the sen, keeping extra digits only for sub-sen per-unit costs
+func formatUnitCost(v float64) string {
+	if math.Round(v*100)/100 == v {
+		return fmt.Sprintf("%.2f", v)
+	}
+	return formatQty(v)
+}
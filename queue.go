@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Ticket flags staff can set on an order
+const (
+	FlagRush = "rush"
+	FlagVIP  = "vip"
+)
+
+// Active tickets in the shared kitchen sequence: positioned tickets in position order, then
+// unpositioned ones (new since the last reorder, or reopened) in arrival order
+const activeOrdersQuery = `SELECT ` + orderColumns + ` FROM orders
+	WHERE (status = 'Paid' OR (status = 'Cancelled' AND kitchen_alert = 'VOID'))
+	  AND created_at >= datetime('now', '-24 hours')
+	ORDER BY queue_position IS NULL, queue_position ASC, id ASC`
+
+// activeOrderIDs returns the current kitchen sequence
+func activeOrderIDs() []int {
+	var ids []int
+	for _, o := range getOrdersByQuery(activeOrdersQuery) {
+		ids = append(ids, o.ID)
+	}
+	return ids
+}
+
+// saveQueue persists a sequence so every kitchen screen shows the same order
+func saveQueue(ids []int) {
+	for i, id := range ids {
+		if _, err := db.Exec("UPDATE orders SET queue_position = ? WHERE id = ?", i+1, id); err != nil {
+			fmt.Printf("Error saving queue: %v", err)
+		}
+	}
+}
+
+// moveOrder places an order before another one (beforeID = 0 moves it to the end)
+func moveOrder(id, beforeID int) {
+	var ids []int
+	for _, other := range activeOrderIDs() {
+		if other != id {
+			ids = append(ids, other)
+		}
+	}
+
+	pos := len(ids)
+	for i, other := range ids {
+		if other == beforeID {
+			pos = i
+			break
+		}
+	}
+	ids = append(ids[:pos], append([]int{id}, ids[pos:]...)...)
+	saveQueue(ids)
+}
+
+// shiftOrder moves an order one place earlier (delta = -1) or later (delta = 1)
+func shiftOrder(id, delta int) {
+	ids := activeOrderIDs()
+	for i, other := range ids {
+		if other == id {
+			j := i + delta
+			if j >= 0 && j < len(ids) {
+				ids[i], ids[j] = ids[j], ids[i]
+				saveQueue(ids)
+			}
+			return
+		}
+	}
+}
+
+// toggleFlag sets or clears a flag; newly rushed orders jump to the front of the queue
+func toggleFlag(id int, flag string) {
+	var current string
+	db.QueryRow("SELECT COALESCE(flag, '') FROM orders WHERE id = ?", id).Scan(&current)
+
+	newFlag := flag
+	if current == flag {
+		newFlag = ""
+	}
+	if _, err := db.Exec("UPDATE orders SET flag = ? WHERE id = ?", newFlag, id); err != nil {
+		fmt.Printf("Error setting flag: %v", err)
+		return
+	}
+
+	if newFlag == FlagRush {
+		ids := activeOrderIDs()
+		if len(ids) > 0 {
+			moveOrder(id, ids[0])
+		}
+	}
+}
+
+// handleKitchenFlag toggles rush/VIP from the ticket buttons
+func handleKitchenFlag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	flag := r.URL.Query().Get("flag")
+	if flag != FlagRush && flag != FlagVIP {
+		http.Error(w, "Unknown flag", http.StatusBadRequest)
+		return
+	}
+	if orderLocked(id) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+
+	toggleFlag(id, flag)
+	recordOrderEvent(id, "flag", flag)
+	handleGetKitchenOrders(w, r)
+}
+
+// handleKitchenMove reorders the queue after a drag and drop
+func handleKitchenMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	beforeID, _ := strconv.Atoi(r.URL.Query().Get("before"))
+
+	moveOrder(id, beforeID)
+	recordOrderEvent(id, "move", fmt.Sprintf("before #%d", beforeID))
+	handleGetKitchenOrders(w, r)
+}
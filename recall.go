@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type OrderEvent struct {
+	Event     string
+	Detail    string
+	CreatedAt string
+}
+
+// getOrderEvents returns an order's history, oldest first
+func getOrderEvents(orderID int) []OrderEvent {
+	rows, err := db.Query("SELECT event, COALESCE(detail, ''), created_at FROM order_events WHERE order_id = ? ORDER BY id ASC", orderID)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var events []OrderEvent
+	for rows.Next() {
+		var e OrderEvent
+		rows.Scan(&e.Event, &e.Detail, &e.CreatedAt)
+		events = append(events, e)
+	}
+	return events
+}
+
+// localInputToUTC converts a datetime-local form value to the UTC format stored by SQLite
+func localInputToUTC(value string) string {
+	t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// 1. Recall panel skeleton (search form + results area)
+func handleKitchenRecallPanel(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, `
+	<div class="panel-overlay" onclick="if(event.target === this) this.remove()">
+		<div class="panel" style="width: min(720px, 100vw);">
+			<div class="panel-header">
+				<h2>🔎 Recall</h2>
+				<div class="icon-btn" onclick="this.closest('.panel-overlay').remove()">✕</div>
+			</div>
+			<form class="recall-form" hx-get="/kitchen/recall/search" hx-target="#recall-results" hx-trigger="load, submit, input delay:400ms">
+				<input type="text" name="q" placeholder="Pickup #, name, phone or item..." autofocus>
+				<div style="display:flex; gap:8px;">
+					<input type="datetime-local" name="from" title="From">
+					<input type="datetime-local" name="to" title="To">
+				</div>
+			</form>
+			<div id="recall-results" class="panel-body"></div>
+		</div>
+	</div>`)
+}
+
+// 2. Search all orders
+func handleKitchenRecallSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	query := `SELECT ` + orderColumns + ` FROM orders o WHERE 1 = 1`
+	var args []interface{}
+	if q != "" {
+		like := "%" + q + "%"
+		query += ` AND (pickup_number LIKE ? OR CAST(id AS TEXT) = ? OR customer_name LIKE ? OR customer_phone LIKE ?
+			OR EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.product_name LIKE ?))`
+		args = append(args, like, q, like, like, like)
+	}
+	if from := localInputToUTC(r.URL.Query().Get("from")); from != "" {
+		query += ` AND created_at >= ?`
+		args = append(args, from)
+	}
+	if to := localInputToUTC(r.URL.Query().Get("to")); to != "" {
+		query += ` AND created_at <= ?`
+		args = append(args, to)
+	}
+	query += ` ORDER BY id DESC LIMIT 50`
+
+	orders := getOrdersByQuery(query, args...)
+	if len(orders) == 0 {
+		fmt.Fprint(w, `<p style="color:#555; text-align:center;">No orders found</p>`)
+		return
+	}
+
+	tr := kitchenTranslator()
+	for _, o := range orders {
+		var names []string
+		for _, item := range o.Items {
+			names = append(names, tr.ProductName(item.ProductID, item.Name))
+		}
+		fmt.Fprintf(w, `
+			<button class="panel-item recall-row" hx-get="/kitchen/recall/order?id=%d" hx-target="#recall-results">
+				<span><b>#%s</b> %s</span>
+				<small>%s · %s<br>%s</small>
+			</button>`,
+			o.ID, o.Number(), html.EscapeString(o.Customer), o.Status, parseDBTime(o.CreatedAt).Local().Format("Jan 2, 3:04 pm"),
+			html.EscapeString(strings.Join(names, ", ")))
+	}
+}
+
+// 3. Order details with event history, reopen and reprint
+func handleKitchenRecallOrder(w http.ResponseWriter, r *http.Request) {
+	o, ok := getOrder(r.URL.Query().Get("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	tr := kitchenTranslator()
+	fmt.Fprintf(w, `
+		<button class="icon-btn" style="width:auto; padding:0 15px; font-size:1rem;" hx-get="/kitchen/recall/search" hx-include=".recall-form" hx-target="#recall-results">⬅ Results</button>
+		<h2 style="margin-bottom:0;">#%s <small style="color:#888;">ref %d</small></h2>
+		<p style="color:#aaa; margin-top:4px;">%s %s · %s · RM%.2f</p>
+		<ul class="ticket-items">`,
+		o.Number(), o.ID, html.EscapeString(o.Customer), html.EscapeString(o.Phone), o.Status, o.Total)
+	for _, item := range o.Items {
+		if item.Voided {
+			fmt.Fprintf(w, `<li class="void-item"><s>%s</s> VOID</li>`, tr.ProductName(item.ProductID, item.Name))
+			continue
+		}
+		fmt.Fprintf(w, `<li>%s %s</li>`, tr.ProductName(item.ProductID, item.Name), ticketOptionsHTML(item, tr))
+	}
+	fmt.Fprint(w, `</ul><h3 class="panel-cat">HISTORY</h3><table class="history">`)
+	for _, e := range getOrderEvents(o.ID) {
+		fmt.Fprintf(w, `<tr><td>%s</td><td>%s</td><td>%s</td></tr>`,
+			parseDBTime(e.CreatedAt).Local().Format("Jan 2, 3:04:05 pm"), e.Event, html.EscapeString(e.Detail))
+	}
+	fmt.Fprintf(w, `</table>
+		<div style="display:flex; gap:10px; margin-top:20px;">
+			<button class="btn-kds btn-restore" hx-post="/kitchen/recall/reopen?id=%d" hx-target="#recall-results">↩ Reopen</button>
+			<button class="btn-kds btn-pickup" onclick="window.open('/kitchen/ticket?id=%d', '_blank')">🖨 Reprint</button>
+			<button class="btn-kds btn-void" hx-get="/orders/edit?id=%d" hx-target="body" hx-swap="beforeend">✎ Edit / Cancel</button>
+		</div>`, o.ID, o.ID, o.ID)
+}
+
+// handleKitchenRecallReopen puts an order back on the active board
+func handleKitchenRecallReopen(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if orderLocked(id) {
+		http.Error(w, "This order's business day is closed", http.StatusConflict)
+		return
+	}
+	if err := updateOrderStatus(id, StatusPaid); err != nil {
+		fmt.Printf("Error reopening: %v", err)
+	}
+	recordOrderEvent(id, "reopen", "recall panel")
+	handleKitchenRecallOrder(w, r)
+}
+
+// handleKitchenTicketPrint renders a printable kitchen ticket and logs the reprint
+func handleKitchenTicketPrint(w http.ResponseWriter, r *http.Request) {
+	o, ok := getOrder(r.URL.Query().Get("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	recordOrderEvent(o.ID, "reprint", "")
+	tr := kitchenTranslator()
+
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Ticket #%s</title>
+    <style>
+        body { font-family: monospace; width: 72mm; margin: 0 auto; padding: 4mm 0; }
+        h1 { text-align: center; font-size: 28pt; margin: 0; }
+        .meta { text-align: center; border-bottom: 1px dashed #000; padding-bottom: 6px; }
+        li { font-size: 12pt; font-weight: bold; margin: 6px 0; }
+        .opt { display: block; font-weight: normal; font-size: 10pt; margin-left: 8px; }
+        .allergy { font-size: 16pt; font-weight: bold; text-align: center; border: 3px solid #000; padding: 4px; margin-top: 6px; }
+    </style>
+</head>
+<body onload="window.print()">
+    <h1>#%s</h1>
+    <div class="meta">%s<br>%s<br>** REPRINT **</div>`,
+		o.Number(), o.Number(), html.EscapeString(o.Customer), parseDBTime(o.CreatedAt).Local().Format("Jan 2, 3:04 pm"))
+	if o.Allergy != "" {
+		fmt.Fprintf(w, `
+    <div class="allergy">** ALLERGY: %s **</div>`, html.EscapeString(o.Allergy))
+	}
+	fmt.Fprint(w, `
+    <ul style="list-style:none; padding:0;">`)
+	for _, item := range o.Items {
+		if item.Voided {
+			continue
+		}
+		opts := ""
+		if mods := item.ModifierNames(); len(mods) > 0 {
+			opts = fmt.Sprintf(`<span class="opt">+ %s</span>`, html.EscapeString(strings.Join(tr.Modifiers(mods), ", ")))
+		}
+		if remark := item.Remark(); remark != "" {
+			opts += fmt.Sprintf(`<span class="opt">** %s **</span>`, html.EscapeString(remark))
+		}
+		fmt.Fprintf(w, `<li>%s %s</li>`, html.EscapeString(tr.ProductName(item.ProductID, item.Name)), opts)
+	}
+	fmt.Fprint(w, `</ul>
+</body>
+</html>`)
+}
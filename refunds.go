@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// RefundProcessor sends money back to the customer through the payment provider
+type RefundProcessor interface {
+	Refund(orderID int, amount float64, reason string) (status string, err error)
+}
+
+// refundProcessor is swapped for a real provider (e.g. Stripe) once card payments go live.
+// Until then refunds are queued for the cashier to hand back manually.
+var refundProcessor RefundProcessor = manualRefunds{}
+
+type manualRefunds struct{}
+
+func (manualRefunds) Refund(orderID int, amount float64, reason string) (string, error) {
+	log.Printf("Manual refund needed: order %d, RM%.2f (%s)", orderID, amount, reason)
+	return "pending", nil
+}
+
+// issueRefund triggers a refund and records it against the order,
+// never handing back more than what is left of the order total
+func issueRefund(orderID int, amount float64, reason string) error {
+	if remaining := refundableAmount(orderID); amount > remaining {
+		amount = remaining
+	}
+	if amount < 0.005 {
+		return nil
+	}
+	status, err := refundProcessor.Refund(orderID, amount, reason)
+	if err != nil {
+		status = "failed"
+	}
+	_, dbErr := db.Exec("INSERT INTO refunds (order_id, amount, reason, status) VALUES (?, ?, ?, ?)", orderID, amount, reason, status)
+	if dbErr != nil {
+		return dbErr
+	}
+	recordOrderEvent(orderID, "refund", fmt.Sprintf("RM%.2f %s (%s)", amount, status, reason))
+	return err
+}
+
+// refundedAmount is the total already refunded for an order
+func refundedAmount(orderID int) float64 {
+	var total float64
+	db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE order_id = ? AND status != 'failed'", orderID).Scan(&total)
+	return total
+}
+
+// refundableAmount is what the customer actually paid (amend surcharges only once collected)
+// less earlier refunds
+func refundableAmount(orderID int) float64 {
+	var total float64
+	db.QueryRow("SELECT total_amount FROM orders WHERE id = ?", orderID).Scan(&total)
+	return total - refundedAmount(orderID)
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Reporting periods: SQL expression grouping business days into buckets
+var reportPeriods = map[string]string{
+	"day":   "o.business_day",
+	"week":  "strftime('%Y-W%W', o.business_day)",
+	"month": "substr(o.business_day, 1, 7)",
+}
+
+const reportTopN = 10
+
+// Sales count everything that wasn't cancelled; refunds are subtracted separately
+const reportOrderFilter = "o.status != 'Cancelled' AND o.business_day BETWEEN ? AND ?"
+
+type reportRange struct {
+	From, To, Period string
+}
+
+// reportRangeFrom reads the range from the query string (default: last 30 business days, by day)
+func reportRangeFrom(q url.Values) reportRange {
+	rr := reportRange{From: q.Get("from"), To: q.Get("to"), Period: q.Get("period")}
+	if rr.To == "" {
+		rr.To = businessDay(time.Now())
+	}
+	if rr.From == "" {
+		rr.From = businessDay(time.Now().AddDate(0, 0, -29))
+	}
+	if _, ok := reportPeriods[rr.Period]; !ok {
+		rr.Period = "day"
+	}
+	return rr
+}
+
+type revenueRow struct {
+	Period  string
+	Orders  int
+	Gross   float64
+	Refunds float64
+}
+
+func (r revenueRow) Net() float64 { return r.Gross - r.Refunds }
+
+// AvgTicket is net revenue per order, so refunds bring it down like they do the net column
+func (r revenueRow) AvgTicket() float64 {
+	if r.Orders == 0 {
+		return 0
+	}
+	return r.Net() / float64(r.Orders)
+}
+
+func revenueReport(rr reportRange) []revenueRow {
+	rows, err := db.Query(`
+		SELECT `+reportPeriods[rr.Period]+` AS period, COUNT(*), SUM(o.total_amount),
+		       SUM(COALESCE((SELECT SUM(amount) FROM refunds rf WHERE rf.order_id = o.id AND rf.status != 'failed'), 0))
+		FROM orders o
+		WHERE `+reportOrderFilter+`
+		GROUP BY period ORDER BY period`, rr.From, rr.To)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var out []revenueRow
+	for rows.Next() {
+		var row revenueRow
+		rows.Scan(&row.Period, &row.Orders, &row.Gross, &row.Refunds)
+		out = append(out, row)
+	}
+	return out
+}
+
+type salesRow struct {
+	Name    string
+	Qty     int
+	Revenue float64
+}
+
+// salesBy groups sold (non-voided) lines by an expression such as the product or category name
+func salesBy(rr reportRange, groupExpr string, limit int) []salesRow {
+	rows, err := db.Query(`
+		SELECT `+groupExpr+` AS label, COUNT(*), SUM(oi.price)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		LEFT JOIN products p ON p.id = oi.product_id
+		WHERE `+reportOrderFilter+` AND COALESCE(oi.voided, 0) = 0
+		GROUP BY label ORDER BY SUM(oi.price) DESC LIMIT ?`, rr.From, rr.To, limit)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var out []salesRow
+	for rows.Next() {
+		var row salesRow
+		rows.Scan(&row.Name, &row.Qty, &row.Revenue)
+		out = append(out, row)
+	}
+	return out
+}
+
+type attachRow struct {
+	Addon    string
+	Attached int // Lines that had the add-on
+	Eligible int // Lines of products offering it (same menu option type)
+}
+
+func (a attachRow) Rate() float64 {
+	if a.Eligible == 0 {
+		return 0
+	}
+	return float64(a.Attached) / float64(a.Eligible) * 100
+}
+
+// addonAttachReport: how often each paid add-on is chosen on the products that offer it
+func addonAttachReport(rr reportRange) []attachRow {
+	rows, err := db.Query(`
+		SELECT oio.option_name, COUNT(DISTINCT oi.id),
+		       (SELECT COUNT(*) FROM order_items oi2
+		        JOIN orders o2 ON o2.id = oi2.order_id
+		        JOIN products p2 ON p2.id = oi2.product_id
+		        WHERE p2.type_tag = p.type_tag AND COALESCE(oi2.voided, 0) = 0
+		          AND o2.status != 'Cancelled' AND o2.business_day BETWEEN ? AND ?)
+		FROM order_item_options oio
+		JOIN order_items oi ON oi.id = oio.order_item_id
+		JOIN orders o ON o.id = oi.order_id
+		JOIN products p ON p.id = oi.product_id
+		WHERE `+reportOrderFilter+` AND COALESCE(oi.voided, 0) = 0 AND oio.group_name = 'Add-ons'
+		GROUP BY oio.option_name, p.type_tag
+		ORDER BY oio.option_name`, rr.From, rr.To, rr.From, rr.To)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var out []attachRow
+	for rows.Next() {
+		var row attachRow
+		rows.Scan(&row.Addon, &row.Attached, &row.Eligible)
+		out = append(out, row)
+	}
+	return out
+}
+
+var weekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// heatmapReport counts orders per [weekday][hour] in local time
+func heatmapReport(rr reportRange) [7][24]int {
+	var grid [7][24]int
+	rows, err := db.Query(`
+		SELECT CAST(strftime('%w', o.created_at, 'localtime') AS INTEGER), CAST(strftime('%H', o.created_at, 'localtime') AS INTEGER), COUNT(*)
+		FROM orders o
+		WHERE `+reportOrderFilter+`
+		GROUP BY 1, 2`, rr.From, rr.To)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return grid
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day, hour, n int
+		rows.Scan(&day, &hour, &n)
+		grid[day][hour] = n
+	}
+	return grid
+}
+
+// barHTML renders a simple horizontal bar scaled against max
+func barHTML(value, max float64, color string) string {
+	width := 0.0
+	if max > 0 {
+		width = value / max * 100
+	}
+	return fmt.Sprintf(`<div class="h-3 rounded %s" style="width: %.1f%%"></div>`, color, width)
+}
+
+// handleAdminReports renders the sales dashboard
+func handleAdminReports(w http.ResponseWriter, r *http.Request) {
+	rr := reportRangeFrom(r.URL.Query())
+
+	adminPageStart(w, "Sales Reports")
+	fmt.Fprintf(w, `
+    <form method="get" class="bg-white rounded-lg shadow p-4 flex flex-wrap items-end gap-4 text-sm">
+        <label class="flex flex-col">From <input type="date" name="from" value="%s" class="border rounded px-2 py-1"></label>
+        <label class="flex flex-col">To <input type="date" name="to" value="%s" class="border rounded px-2 py-1"></label>
+        <label class="flex flex-col">Group by %s</label>
+        <button class="bg-gray-900 text-white px-4 py-1.5 rounded font-semibold">Update</button>
+        <a href="/admin/reports/menu?%s" class="ml-auto text-blue-600 hover:underline">🍽 Menu engineering &amp; margins</a>
+    </form>`, html.EscapeString(rr.From), html.EscapeString(rr.To),
+		filterSelect("period", rr.Period, []string{"day", "week", "month"}),
+		html.EscapeString(url.Values{"from": {rr.From}, "to": {rr.To}}.Encode()))
+
+	exportLink := func(report string) string {
+		q := url.Values{"from": {rr.From}, "to": {rr.To}, "period": {rr.Period}, "report": {report}}
+		return fmt.Sprintf(`<a href="/admin/reports/export?%s" class="text-sm font-normal text-blue-600 hover:underline">⬇ CSV</a>`, html.EscapeString(q.Encode()))
+	}
+
+	// Revenue by period
+	revenue := revenueReport(rr)
+	var totals revenueRow
+	maxNet := 0.0
+	for _, row := range revenue {
+		totals.Orders += row.Orders
+		totals.Gross += row.Gross
+		totals.Refunds += row.Refunds
+		if row.Net() > maxNet {
+			maxNet = row.Net()
+		}
+	}
+	fmt.Fprintf(w, `
+    <div class="grid grid-cols-2 md:grid-cols-4 gap-4">
+        <div class="bg-white rounded-lg shadow p-4"><p class="text-xs text-gray-500 uppercase">Net revenue</p><p class="text-2xl font-black">RM%.2f</p></div>
+        <div class="bg-white rounded-lg shadow p-4"><p class="text-xs text-gray-500 uppercase">Orders</p><p class="text-2xl font-black">%d</p></div>
+        <div class="bg-white rounded-lg shadow p-4"><p class="text-xs text-gray-500 uppercase">Average ticket (net)</p><p class="text-2xl font-black">RM%.2f</p></div>
+        <div class="bg-white rounded-lg shadow p-4"><p class="text-xs text-gray-500 uppercase">Refunds</p><p class="text-2xl font-black text-red-600">RM%.2f</p></div>
+    </div>
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4 flex justify-between">Revenue by %s %s</h2>
+        <table class="w-full text-sm">
+            <thead class="text-left text-gray-500"><tr><th>Period</th><th>Orders</th><th>Avg ticket (net)</th><th>Gross</th><th>Refunds</th><th>Net</th><th class="w-1/3"></th></tr></thead><tbody>`,
+		totals.Net(), totals.Orders, totals.AvgTicket(), totals.Refunds, rr.Period, exportLink("revenue"))
+	for _, row := range revenue {
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>%d</td><td>RM%.2f</td><td>RM%.2f</td><td>RM%.2f</td><td class="font-semibold">RM%.2f</td><td>%s</td></tr>`,
+			row.Period, row.Orders, row.AvgTicket(), row.Gross, row.Refunds, row.Net(), barHTML(row.Net(), maxNet, "bg-orange-500"))
+	}
+	if len(revenue) == 0 {
+		fmt.Fprint(w, `<tr><td colspan="7" class="py-6 text-center text-gray-400">No sales in this range</td></tr>`)
+	}
+	fmt.Fprint(w, `</tbody></table></section>
+    <div class="grid md:grid-cols-2 gap-6">`)
+
+	// Top products and categories
+	for _, section := range []struct {
+		Title, Report string
+		Rows          []salesRow
+	}{
+		{"Top products", "products", salesBy(rr, "oi.product_name", reportTopN)},
+		{"Categories", "categories", salesBy(rr, "COALESCE(p.category, 'other')", reportTopN)},
+	} {
+		fmt.Fprintf(w, `
+        <section class="bg-white rounded-lg shadow p-6">
+            <h2 class="font-bold text-lg mb-4 flex justify-between">%s %s</h2>
+            <table class="w-full text-sm"><tbody>`, section.Title, exportLink(section.Report))
+		maxRevenue := 0.0
+		if len(section.Rows) > 0 {
+			maxRevenue = section.Rows[0].Revenue
+		}
+		for _, row := range section.Rows {
+			fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>%dx</td><td>RM%.2f</td><td class="w-1/3">%s</td></tr>`,
+				html.EscapeString(row.Name), row.Qty, row.Revenue, barHTML(row.Revenue, maxRevenue, "bg-blue-500"))
+		}
+		fmt.Fprint(w, `</tbody></table></section>`)
+	}
+
+	// Add-on attach rates
+	fmt.Fprintf(w, `
+        <section class="bg-white rounded-lg shadow p-6">
+            <h2 class="font-bold text-lg mb-4 flex justify-between">Add-on attach rate %s</h2>
+            <table class="w-full text-sm"><tbody>`, exportLink("addons"))
+	for _, row := range addonAttachReport(rr) {
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>%d / %d</td><td>%.1f%%</td><td class="w-1/3">%s</td></tr>`,
+			row.Addon, row.Attached, row.Eligible, row.Rate(), barHTML(row.Rate(), 100, "bg-green-500"))
+	}
+	fmt.Fprint(w, `</tbody></table></section>`)
+
+	// Busy hours heatmap
+	grid := heatmapReport(rr)
+	maxCell := 0
+	for _, day := range grid {
+		for _, n := range day {
+			if n > maxCell {
+				maxCell = n
+			}
+		}
+	}
+	fmt.Fprintf(w, `
+        <section class="bg-white rounded-lg shadow p-6 md:col-span-2 overflow-x-auto">
+            <h2 class="font-bold text-lg mb-4 flex justify-between">Orders by hour and weekday %s</h2>
+            <table class="text-xs text-center"><thead><tr><th></th>`, exportLink("heatmap"))
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(w, `<th class="px-1 font-normal text-gray-500">%02d</th>`, hour)
+	}
+	fmt.Fprint(w, `</tr></thead><tbody>`)
+	for day, counts := range grid {
+		fmt.Fprintf(w, `<tr><th class="pr-2 text-left">%s</th>`, weekdayNames[day])
+		for _, n := range counts {
+			opacity := 0.0
+			if maxCell > 0 {
+				opacity = float64(n) / float64(maxCell)
+			}
+			label := ""
+			if n > 0 {
+				label = strconv.Itoa(n)
+			}
+			fmt.Fprintf(w, `<td class="w-8 h-8 border border-white" style="background: rgba(234, 88, 12, %.2f)" title="%d orders">%s</td>`,
+				opacity, n, label)
+		}
+		fmt.Fprint(w, `</tr>`)
+	}
+	fmt.Fprint(w, `</tbody></table></section>
+    </div>`)
+	adminPageEnd(w)
+}
+
+// handleAdminReportsExport downloads one of the report tables as CSV
+func handleAdminReportsExport(w http.ResponseWriter, r *http.Request) {
+	rr := reportRangeFrom(r.URL.Query())
+	report := r.URL.Query().Get("report")
+
+	var records [][]string
+	switch report {
+	case "revenue":
+		records = append(records, []string{"period", "orders", "gross", "refunds", "net", "avg_net_ticket"})
+		for _, row := range revenueReport(rr) {
+			records = append(records, []string{row.Period, strconv.Itoa(row.Orders),
+				fmt.Sprintf("%.2f", row.Gross), fmt.Sprintf("%.2f", row.Refunds), fmt.Sprintf("%.2f", row.Net()), fmt.Sprintf("%.2f", row.AvgTicket())})
+		}
+	case "products", "categories":
+		expr := "oi.product_name"
+		if report == "categories" {
+			expr = "COALESCE(p.category, 'other')"
+		}
+		records = append(records, []string{"name", "qty", "revenue"})
+		for _, row := range salesBy(rr, expr, -1) {
+			records = append(records, []string{row.Name, strconv.Itoa(row.Qty), fmt.Sprintf("%.2f", row.Revenue)})
+		}
+	case "addons":
+		records = append(records, []string{"addon", "attached", "eligible", "attach_rate_pct"})
+		for _, row := range addonAttachReport(rr) {
+			records = append(records, []string{row.Addon, strconv.Itoa(row.Attached), strconv.Itoa(row.Eligible), fmt.Sprintf("%.1f", row.Rate())})
+		}
+	case "heatmap":
+		header := []string{"weekday"}
+		for hour := 0; hour < 24; hour++ {
+			header = append(header, fmt.Sprintf("%02d", hour))
+		}
+		records = append(records, header)
+		for day, counts := range heatmapReport(rr) {
+			record := []string{weekdayNames[day]}
+			for _, n := range counts {
+				record = append(record, strconv.Itoa(n))
+			}
+			records = append(records, record)
+		}
+	default:
+		http.Error(w, "Unknown report", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s_%s.csv"`, report, rr.From, rr.To))
+	csv.NewWriter(w).WriteAll(records)
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Availability schedules: a window limits a product, or a whole category, to certain
+// days of the week, a time of day and/or a date range. Something with no windows is
+// always on the menu; otherwise at least one of its windows must match. Product and
+// category windows both apply (a weekend-only item in a breakfast category shows on
+// weekend mornings only).
+
+type AvailabilityWindow struct {
+	ID        int
+	ProductID int    // 0 for category windows
+	Category  string // "" for product windows
+	Target    string // product or category name, for display
+	Days      string // comma-separated time.Weekday numbers; "" = every day
+	StartTime string // "15:04"; "" = all day
+	EndTime   string // may be before StartTime to run past midnight
+	StartDate string // "2006-01-02"; "" = open-ended
+	EndDate   string
+}
+
+var weekdayShort = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// Matches reports whether the window is open at t (local time)
+func (aw AvailabilityWindow) Matches(t time.Time) bool {
+	date := t.Format("2006-01-02")
+	if aw.StartDate != "" && date < aw.StartDate {
+		return false
+	}
+	if aw.EndDate != "" && date > aw.EndDate {
+		return false
+	}
+
+	day := t.Weekday()
+	clock := t.Format("15:04")
+	if aw.StartTime != "" && aw.EndTime != "" && aw.EndTime <= aw.StartTime && clock < aw.EndTime {
+		// After midnight in an overnight window: it belongs to the previous day's opening
+		day = (day + 6) % 7
+	}
+	if aw.Days != "" && !strings.Contains(","+aw.Days+",", fmt.Sprintf(",%d,", day)) {
+		return false
+	}
+
+	switch {
+	case aw.StartTime == "" || aw.EndTime == "":
+		return (aw.StartTime == "" || clock >= aw.StartTime) && (aw.EndTime == "" || clock < aw.EndTime)
+	case aw.StartTime < aw.EndTime:
+		return clock >= aw.StartTime && clock < aw.EndTime
+	default:
+		return clock >= aw.StartTime || clock < aw.EndTime
+	}
+}
+
+// Describe is the human-readable rule, e.g. "Sat, Sun · 07:00–11:00"
+func (aw AvailabilityWindow) Describe() string {
+	var parts []string
+	if aw.Days == "" {
+		parts = append(parts, "Every day")
+	} else {
+		var days []string
+		for _, d := range strings.Split(aw.Days, ",") {
+			if n, err := strconv.Atoi(d); err == nil && n >= 0 && n < 7 {
+				days = append(days, weekdayShort[n])
+			}
+		}
+		parts = append(parts, strings.Join(days, ", "))
+	}
+	if aw.StartTime != "" || aw.EndTime != "" {
+		parts = append(parts, fmt.Sprintf("%s–%s", orDefault(aw.StartTime, "open"), orDefault(aw.EndTime, "close")))
+	}
+	if aw.StartDate != "" || aw.EndDate != "" {
+		parts = append(parts, fmt.Sprintf("%s to %s", orDefault(aw.StartDate, "…"), orDefault(aw.EndDate, "…")))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func getAvailabilityWindows() []AvailabilityWindow {
+	rows, err := db.Query(`
+		SELECT w.id, COALESCE(w.product_id, 0), COALESCE(w.category, ''), COALESCE(p.name, w.category),
+		       COALESCE(w.days, ''), COALESCE(w.start_time, ''), COALESCE(w.end_time, ''), COALESCE(w.start_date, ''), COALESCE(w.end_date, '')
+		FROM availability_windows w LEFT JOIN products p ON p.id = w.product_id
+		ORDER BY COALESCE(w.category, '') = '', COALESCE(p.name, w.category), w.id`)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var out []AvailabilityWindow
+	for rows.Next() {
+		var aw AvailabilityWindow
+		rows.Scan(&aw.ID, &aw.ProductID, &aw.Category, &aw.Target, &aw.Days, &aw.StartTime, &aw.EndTime, &aw.StartDate, &aw.EndDate)
+		out = append(out, aw)
+	}
+	return out
+}
+
+// menuSchedule holds every window, indexed for checking products against a point in time
+type menuSchedule struct {
+	byProduct  map[int][]AvailabilityWindow
+	byCategory map[string][]AvailabilityWindow
+}
+
+func loadMenuSchedule() menuSchedule {
+	s := menuSchedule{byProduct: map[int][]AvailabilityWindow{}, byCategory: map[string][]AvailabilityWindow{}}
+	for _, aw := range getAvailabilityWindows() {
+		if aw.ProductID > 0 {
+			s.byProduct[aw.ProductID] = append(s.byProduct[aw.ProductID], aw)
+		} else {
+			s.byCategory[aw.Category] = append(s.byCategory[aw.Category], aw)
+		}
+	}
+	return s
+}
+
+func anyWindowOpen(windows []AvailabilityWindow, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, aw := range windows {
+		if aw.Matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Available reports whether the product is on the menu at t
+func (s menuSchedule) Available(productID int, category string, t time.Time) bool {
+	return anyWindowOpen(s.byCategory[category], t) && anyWindowOpen(s.byProduct[productID], t)
+}
+
+// productScheduledNow looks up a single product against the schedule at the current time
+func productScheduledNow(productID int) bool {
+	var category string
+	if err := db.QueryRow("SELECT category FROM products WHERE id = ?", productID).Scan(&category); err != nil {
+		return false
+	}
+	return loadMenuSchedule().Available(productID, category, time.Now())
+}
+
+// unscheduledCartItems names the cart lines that aren't on the menu right now
+func unscheduledCartItems() []string {
+	schedule := loadMenuSchedule()
+	now := time.Now()
+	var off []string
+	for _, item := range cart {
+		var category string
+		db.QueryRow("SELECT category FROM products WHERE id = ?", item.ProductID).Scan(&category)
+		if !schedule.Available(item.ProductID, category, now) {
+			off = append(off, item.Name)
+		}
+	}
+	return off
+}
+
+func redirectToSchedules(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, "/admin/schedules?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// handleAdminSchedules lists availability windows and previews the menu at any date and time
+func handleAdminSchedules(w http.ResponseWriter, r *http.Request) {
+	at := time.Now()
+	if v := r.URL.Query().Get("at"); v != "" {
+		if t, err := time.ParseInLocation("2006-01-02T15:04", v, time.Local); err == nil {
+			at = t
+		}
+	}
+	schedule := loadMenuSchedule()
+
+	adminPageStart(w, "Menu Schedules")
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		fmt.Fprintf(w, `<div class="bg-green-50 border border-green-200 text-green-800 rounded p-3">%s</div>`, html.EscapeString(msg))
+	}
+
+	// Windows
+	fmt.Fprint(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <h2 class="font-bold text-lg mb-4">⏰ Availability windows</h2>
+        <p class="text-sm text-gray-500 mb-4">Items without a window are always available. With windows, an item shows when any of its windows is open; category and product windows both apply.</p>
+        <table class="w-full text-sm"><tbody>`)
+	windows := getAvailabilityWindows()
+	for _, aw := range windows {
+		target := html.EscapeString(aw.Target)
+		if aw.ProductID == 0 {
+			target = `<span class="uppercase text-gray-500">` + target + `</span> <small class="text-gray-400">(category)</small>`
+		}
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1 font-semibold">%s</td><td>%s</td>
+            <td class="text-right"><form method="post" action="/admin/schedules/delete"><input type="hidden" name="id" value="%d"><button class="text-red-600 hover:underline">Remove</button></form></td></tr>`,
+			target, html.EscapeString(aw.Describe()), aw.ID)
+	}
+	if len(windows) == 0 {
+		fmt.Fprint(w, `<tr><td class="py-6 text-center text-gray-400">No schedules — everything is available all the time</td></tr>`)
+	}
+	fmt.Fprint(w, `</tbody></table>
+        <form method="post" action="/admin/schedules/add" class="flex flex-wrap items-end gap-3 mt-4 text-sm">
+            <select name="target" required class="border rounded px-2 py-1"><optgroup label="Categories">`)
+	var products []Product
+	categories := map[string]int{}
+	rows, err := db.Query("SELECT id, name, category FROM products ORDER BY category, name")
+	if err == nil {
+		for rows.Next() {
+			var p Product
+			rows.Scan(&p.ID, &p.Name, &p.Category)
+			products = append(products, p)
+			categories[p.Category]++
+		}
+		rows.Close()
+	}
+	for _, cat := range sortedKeys(categories) {
+		fmt.Fprintf(w, `<option value="category:%s">%s</option>`, html.EscapeString(cat), html.EscapeString(strings.ToUpper(cat)))
+	}
+	fmt.Fprint(w, `</optgroup><optgroup label="Products">`)
+	for _, p := range products {
+		fmt.Fprintf(w, `<option value="product:%d">%s</option>`, p.ID, html.EscapeString(p.Name))
+	}
+	fmt.Fprint(w, `</optgroup></select><span class="flex gap-2">`)
+	for i, d := range weekdayShort {
+		fmt.Fprintf(w, `<label><input type="checkbox" name="days" value="%d"> %s</label>`, i, d)
+	}
+	fmt.Fprint(w, `</span>
+            <label class="flex flex-col">From <input type="time" name="start_time" class="border rounded px-2 py-1"></label>
+            <label class="flex flex-col">Until <input type="time" name="end_time" class="border rounded px-2 py-1"></label>
+            <label class="flex flex-col">Start date <input type="date" name="start_date" class="border rounded px-2 py-1"></label>
+            <label class="flex flex-col">End date <input type="date" name="end_date" class="border rounded px-2 py-1"></label>
+            <button class="bg-orange-600 text-white rounded px-3 py-1.5 font-semibold">+ Add window</button>
+        </form>
+    </section>`)
+
+	// Preview
+	fmt.Fprintf(w, `
+    <section class="bg-white rounded-lg shadow p-6">
+        <form method="get" class="flex items-end gap-3 mb-4 text-sm">
+            <h2 class="font-bold text-lg mr-auto">👀 Menu as of %s</h2>
+            <input type="datetime-local" name="at" value="%s" class="border rounded px-2 py-1">
+            <button class="bg-gray-900 text-white px-4 py-1.5 rounded font-semibold">Preview</button>
+        </form>
+        <table class="w-full text-sm"><tbody>`, at.Format("Monday Jan 2, 15:04"), at.Format("2006-01-02T15:04"))
+	lastCat := ""
+	for _, p := range products {
+		if p.Category != lastCat {
+			fmt.Fprintf(w, `<tr><th colspan="2" class="pt-3 text-left uppercase text-gray-500">%s</th></tr>`, html.EscapeString(p.Category))
+			lastCat = p.Category
+		}
+		status := `<span class="text-green-700 font-semibold">✅ On the menu</span>`
+		if !schedule.Available(p.ID, p.Category, at) {
+			status = `<span class="text-gray-400">⏰ Hidden</span>`
+		}
+		fmt.Fprintf(w, `<tr class="border-t"><td class="py-1">%s</td><td>%s</td></tr>`, html.EscapeString(p.Name), status)
+	}
+	fmt.Fprint(w, `</tbody></table></section>`)
+	adminPageEnd(w)
+}
+
+func handleAdminScheduleAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+
+	var productID interface{}
+	category := ""
+	kind, value, _ := strings.Cut(r.FormValue("target"), ":")
+	switch kind {
+	case "product":
+		id, err := strconv.Atoi(value)
+		var exists int
+		if err == nil {
+			db.QueryRow("SELECT COUNT(*) FROM products WHERE id = ?", id).Scan(&exists)
+		}
+		if exists == 0 {
+			http.Error(w, "Unknown product", http.StatusBadRequest)
+			return
+		}
+		productID = id
+	case "category":
+		category = value
+	default:
+		http.Error(w, "Pick a product or category", http.StatusBadRequest)
+		return
+	}
+
+	startTime, endTime := r.FormValue("start_time"), r.FormValue("end_time")
+	if startTime != "" && startTime == endTime {
+		http.Error(w, "Start and end time can't be the same", http.StatusBadRequest)
+		return
+	}
+	startDate, endDate := r.FormValue("start_date"), r.FormValue("end_date")
+	if startDate != "" && endDate != "" && endDate < startDate {
+		http.Error(w, "End date is before start date", http.StatusBadRequest)
+		return
+	}
+
+	_, err := db.Exec(`INSERT INTO availability_windows (product_id, category, days, start_time, end_time, start_date, end_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, productID, category, strings.Join(r.Form["days"], ","), startTime, endTime, startDate, endDate)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	redirectToSchedules(w, r, "Schedule added")
+}
+
+func handleAdminScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	db.Exec("DELETE FROM availability_windows WHERE id = ?", r.FormValue("id"))
+	redirectToSchedules(w, r, "Schedule removed")
+}
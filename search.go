@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Menu search uses an SQLite FTS5 index over name, description, category and search tags.
+// FTS5 is a compile-time option of the sqlite driver: build with `make` (which passes
+// `-tags sqlite_fts5`); a plain `go build` falls back to basic search and says so at startup.
+// Without it the index can't be created and search falls back to matching in Go, with the
+// same synonyms, prefix matching and typo tolerance but simpler ranking.
+// The unicode61 tokenizer treats a run of Chinese, Japanese or Korean characters as one
+// word, so queries in those scripts skip the index and match anywhere inside the text.
+
+var menuSearchFTS bool
+
+// Words customers use that aren't on the menu, mapped to words that are
+var searchSynonyms = map[string][]string{
+	"coke":      {"coke", "cola", "can"},
+	"cola":      {"coke", "cola", "can"},
+	"soda":      {"can", "sprite", "coke"},
+	"pop":       {"can", "sprite", "coke"},
+	"soft":      {"can"},
+	"coffee":    {"coffee", "latte", "espresso", "americano", "cappuccino", "mocha"},
+	"veggie":    {"vegetarian", "mushroom", "veg"},
+	"shrimp":    {"prawn"},
+	"spaghetti": {"pasta", "spaghetti", "aglio"},
+	"noodles":   {"pasta"},
+	"sweet":     {"dessert", "cake", "honey"},
+	"cheesy":    {"cheese"},
+}
+
+var searchWordRe = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// searchTagsSQL is a products row's search tags plus its translated names and descriptions,
+// so customers can search in the language they read the menu in
+const searchTagsSQL = `COALESCE(search_tags, '') || ' ' || COALESCE((SELECT group_concat(COALESCE(t.name, '') || ' ' || COALESCE(t.description, ''), ' ')
+	FROM product_translations t WHERE t.product_id = products.id), '')`
+
+// isCJK reports whether a term is written in a script without spaces between words
+func isCJK(term string) bool {
+	for _, r := range term {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchTerms splits a query into lowercase words
+func searchTerms(query string) []string {
+	return searchWordRe.FindAllString(strings.ToLower(query), -1)
+}
+
+// initMenuSearch creates the FTS5 index (when the driver supports it) and rebuilds it from products
+func initMenuSearch() {
+	_, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS menu_search USING fts5(
+		name, description, category, tags, product_id UNINDEXED,
+		tokenize = 'porter unicode61 remove_diacritics 2'
+	)`)
+	if err != nil {
+		log.Println("WARNING: menu search is running without FTS5 and falls back to basic matching.")
+		log.Println("WARNING: build with `make` (go build -tags sqlite_fts5) to enable it:", err)
+		return
+	}
+	menuSearchFTS = true
+	db.Exec("DELETE FROM menu_search")
+	if _, err := db.Exec(`INSERT INTO menu_search (name, description, category, tags, product_id)
+		SELECT name, COALESCE(description, ''), category, ` + searchTagsSQL + `, id FROM products`); err != nil {
+		fmt.Println("Error building menu search index:", err)
+	}
+}
+
+// syncMenuSearch refreshes one product's index entry after an admin create, update or delete
+func syncMenuSearch(productID int64) {
+	if !menuSearchFTS {
+		return
+	}
+	db.Exec("DELETE FROM menu_search WHERE product_id = ?", productID)
+	_, err := db.Exec(`INSERT INTO menu_search (name, description, category, tags, product_id)
+		SELECT name, COALESCE(description, ''), category, `+searchTagsSQL+`, id FROM products WHERE id = ?`, productID)
+	if err != nil {
+		fmt.Println("Error updating menu search index:", err)
+	}
+}
+
+type searchDoc struct {
+	ID                                int
+	Name, Description, Category, Tags string
+}
+
+func loadSearchDocs() []searchDoc {
+	rows, err := db.Query("SELECT id, name, COALESCE(description, ''), category, " + searchTagsSQL + " FROM products")
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return nil
+	}
+	defer rows.Close()
+	var docs []searchDoc
+	for rows.Next() {
+		var d searchDoc
+		rows.Scan(&d.ID, &d.Name, &d.Description, &d.Category, &d.Tags)
+		docs = append(docs, d)
+	}
+	return docs
+}
+
+// menuVocabulary is every word on the menu, used to correct typos
+func menuVocabulary(docs []searchDoc) map[string]bool {
+	vocab := map[string]bool{}
+	for _, d := range docs {
+		for _, word := range searchTerms(d.Name + " " + d.Description + " " + d.Category + " " + d.Tags) {
+			vocab[word] = true
+		}
+	}
+	return vocab
+}
+
+// levenshtein is the edit distance between two words
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// correctTerm finds the closest menu word for a misspelt term: one edit for short words,
+// two for longer ones. Words the term is a prefix of count as already correct.
+func correctTerm(term string, vocab map[string]bool) (string, bool) {
+	maxDist := 1
+	if len([]rune(term)) >= 6 {
+		maxDist = 2
+	}
+	best, bestDist := "", maxDist+1
+	for word := range vocab {
+		if strings.HasPrefix(word, term) {
+			return term, false
+		}
+		// Compare against the word's start too, so "marg" style partial typos still land
+		candidate := word
+		if r := []rune(word); len(r) > len([]rune(term))+maxDist {
+			candidate = string(r[:len([]rune(term))])
+		}
+		if d := levenshtein(term, candidate); d < bestDist || (d == bestDist && word < best) {
+			best, bestDist = word, d
+		}
+	}
+	if best == "" || bestDist > maxDist {
+		return term, false
+	}
+	return best, true
+}
+
+// expandTerm is the term itself plus its synonyms
+func expandTerm(term string) []string {
+	if syn, ok := searchSynonyms[term]; ok {
+		return syn
+	}
+	return []string{term}
+}
+
+// menuSearch returns matching product IDs, best first, and the words to highlight.
+// Every query word (or one of its synonyms) must match, as a prefix; words with no
+// match on the menu are corrected to the nearest menu word or synonym. CJK words
+// match as substrings and aren't corrected.
+func menuSearch(query string) (ranked []int, highlight []string) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	docs := loadSearchDocs()
+	vocab := menuVocabulary(docs)
+	for word := range searchSynonyms {
+		vocab[word] = true
+	}
+
+	var groups [][]string
+	cjk := false
+	for _, term := range terms {
+		if isCJK(term) {
+			cjk = true
+			groups = append(groups, []string{term})
+			highlight = append(highlight, term)
+			continue
+		}
+		if _, ok := searchSynonyms[term]; !ok {
+			if fixed, ok := correctTerm(term, vocab); ok {
+				term = fixed
+			}
+		}
+		group := expandTerm(term)
+		groups = append(groups, group)
+		highlight = append(highlight, group...)
+	}
+
+	if menuSearchFTS && !cjk {
+		return ftsSearch(groups), highlight
+	}
+	return basicSearch(docs, groups), highlight
+}
+
+// ftsSearch runs the query against the FTS5 index, ranked by bm25 with name matches weighted highest
+func ftsSearch(groups [][]string) []int {
+	var clauses []string
+	for _, group := range groups {
+		var alts []string
+		for _, word := range group {
+			alts = append(alts, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+		}
+		clauses = append(clauses, "("+strings.Join(alts, " OR ")+")")
+	}
+
+	rows, err := db.Query(`SELECT product_id FROM menu_search WHERE menu_search MATCH ?
+		ORDER BY bm25(menu_search, 10.0, 2.0, 4.0, 4.0)`, strings.Join(clauses, " AND "))
+	if err != nil {
+		fmt.Println("Menu search error:", err)
+		return nil
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// termMatches reports whether a text word matches a search term: by prefix, or anywhere inside it for CJK
+func termMatches(word, term string) bool {
+	if isCJK(term) {
+		return strings.Contains(word, term)
+	}
+	return strings.HasPrefix(word, term)
+}
+
+// basicSearch is the fallback when FTS5 isn't compiled in, and handles CJK queries
+func basicSearch(docs []searchDoc, groups [][]string) []int {
+	type hit struct{ id, score int }
+	var hits []hit
+	for _, d := range docs {
+		fields := []struct {
+			words  []string
+			weight int
+		}{
+			{searchTerms(d.Name), 10}, {searchTerms(d.Description), 2}, {searchTerms(d.Category), 4}, {searchTerms(d.Tags), 4},
+		}
+		score := 0
+		for _, group := range groups {
+			groupScore := 0
+			for _, f := range fields {
+				for _, w := range f.words {
+					for _, alt := range group {
+						if termMatches(w, alt) {
+							groupScore += f.weight
+						}
+					}
+				}
+			}
+			if groupScore == 0 {
+				score = 0
+				break
+			}
+			score += groupScore
+		}
+		if score > 0 {
+			hits = append(hits, hit{d.ID, score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.id
+	}
+	return ids
+}
+
+// highlightMatches escapes text and wraps the words that start with a search term in <mark>;
+// CJK terms mark just the matching characters
+func highlightMatches(text string, terms []string) string {
+	if len(terms) == 0 {
+		return html.EscapeString(text)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range searchWordRe.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[loc[0]:loc[1]])
+		for _, term := range terms {
+			start, end := loc[0], loc[1]
+			if isCJK(term) {
+				// CJK has no case, so offsets in the original text line up
+				i := strings.Index(text[start:end], term)
+				if i < 0 {
+					continue
+				}
+				start, end = start+i, start+i+len(term)
+			} else if !strings.HasPrefix(word, term) {
+				continue
+			}
+			b.WriteString(html.EscapeString(text[last:start]))
+			b.WriteString(`<mark class="bg-yellow-200 rounded px-0.5">` + html.EscapeString(text[start:end]) + `</mark>`)
+			last = end
+			break
+		}
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// getSetting reads a value from the settings table, returning def when unset
+func getSetting(key, def string) string {
+	var value string
+	err := db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return def
+	}
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return def
+	}
+	return value
+}
+
+// setSetting stores a value in the settings table
+func setSetting(key, value string) error {
+	_, err := db.Exec(`INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
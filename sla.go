@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Server-side SLA thresholds (minutes from order placement) per category
+var slaMinutes = map[string]int{
+	"drink": 5,
+	"sides": 10,
+	"pasta": 12,
+	"pizza": 15,
+}
+
+const (
+	defaultSLAMinutes = 15
+	slaCheckInterval  = 30 * time.Second
+)
+
+// startSLAMonitor checks active orders against their SLA in the background
+func startSLAMonitor() {
+	go func() {
+		for {
+			checkSLAs()
+			time.Sleep(slaCheckInterval)
+		}
+	}()
+}
+
+func slaFor(category string) int {
+	if m, ok := slaMinutes[category]; ok {
+		return m
+	}
+	return defaultSLAMinutes
+}
+
+// checkSLAs records a breach (once per order and category) for every active
+// order that has gone past its threshold, and alerts the managers.
+func checkSLAs() {
+	type lateLine struct {
+		OrderID  int
+		Number   string
+		Category string
+		Elapsed  time.Duration
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT o.id, COALESCE(o.pickup_number, o.id), o.created_at, COALESCE(p.category, '')
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		LEFT JOIN products p ON p.name = oi.product_name
+		WHERE o.status = 'Paid' AND COALESCE(oi.voided, 0) = 0 AND o.created_at >= datetime('now', '-24 hours')`)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return
+	}
+
+	// Collect first so the read is closed before writing
+	var late []lateLine
+	for rows.Next() {
+		var l lateLine
+		var createdAt string
+		rows.Scan(&l.OrderID, &l.Number, &createdAt, &l.Category)
+		l.Elapsed = time.Since(parseDBTime(createdAt))
+		if l.Elapsed > time.Duration(slaFor(l.Category))*time.Minute {
+			late = append(late, l)
+		}
+	}
+	rows.Close()
+
+	for _, l := range late {
+		// One open breach per order and category; a reopened order can breach again
+		res, err := db.Exec(`INSERT INTO sla_breaches (order_id, category, threshold_minutes)
+			SELECT ?, ?, ? WHERE NOT EXISTS (
+				SELECT 1 FROM sla_breaches WHERE order_id = ? AND category = ? AND resolved_at IS NULL)`,
+			l.OrderID, l.Category, slaFor(l.Category), l.OrderID, l.Category)
+		if err != nil {
+			fmt.Println("Error recording SLA breach:", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // Already raised
+		}
+
+		msg := fmt.Sprintf("Order #%s (%s) is late: %d min against a %d min SLA", l.Number, categoryLabel(l.Category), int(l.Elapsed.Minutes()), slaFor(l.Category))
+		recordOrderEvent(l.OrderID, "sla_breach", msg)
+		notify("Late order", msg)
+	}
+}
+
+// resolveSLABreaches closes open breaches once an order leaves the kitchen
+func resolveSLABreaches(orderID string) {
+	db.Exec("UPDATE sla_breaches SET resolved_at = CURRENT_TIMESTAMP WHERE order_id = ? AND resolved_at IS NULL", orderID)
+}
+
+// lateOrderIDs returns orders with an unresolved SLA breach
+func lateOrderIDs() map[int]bool {
+	late := map[int]bool{}
+	rows, err := db.Query("SELECT DISTINCT order_id FROM sla_breaches WHERE resolved_at IS NULL")
+	if err != nil {
+		return late
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		late[id] = true
+	}
+	return late
+}
+
+func categoryLabel(category string) string {
+	if category == "" {
+		return "other"
+	}
+	return category
+}
+
+// renderSLABanner shows managers which orders are currently late (nothing when all is well)
+func renderSLABanner(w http.ResponseWriter, style string) {
+	rows, err := db.Query(`
+		SELECT COALESCE(o.pickup_number, o.id), GROUP_CONCAT(b.category, ', '), o.created_at
+		FROM sla_breaches b JOIN orders o ON o.id = b.order_id
+		WHERE b.resolved_at IS NULL
+		GROUP BY o.id ORDER BY o.id ASC`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var parts []string
+	for rows.Next() {
+		var number, cats, createdAt string
+		rows.Scan(&number, &cats, &createdAt)
+		mins := int(time.Since(parseDBTime(createdAt)).Minutes())
+		parts = append(parts, fmt.Sprintf("#%s %s (%dm)", number, cats, mins))
+	}
+	if len(parts) == 0 {
+		return
+	}
+	sort.Strings(parts)
+
+	fmt.Fprintf(w, `<div class="sla-banner" style="%s">⚠️ %d LATE: %s</div>`, style, len(parts), strings.Join(parts, " · "))
+}
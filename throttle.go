@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Kitchen load thresholds for online (web) ordering
+const (
+	throttleWaitMinutes  = 35 // Above this, web orders are rate limited
+	throttleOrdersPer10m = 3  // Web orders accepted per 10 minutes while throttled
+	pauseWaitMinutes     = 50 // Above this, web ordering pauses automatically
+	pauseActiveItems     = 30 // ...or when this many items are on the board
+)
+
+const pausedUntilKey = "orders_paused_until"
+
+// orderingStatus reports whether a channel may place orders right now.
+// Kiosk/in-store orders are never blocked; only online checkout is.
+func orderingStatus(channel string) (bool, string) {
+	if channel != "web" {
+		return true, ""
+	}
+
+	if until := pausedUntil(); time.Now().Before(until) {
+		return false, fmt.Sprintf("Online ordering is paused until %s. Please check back soon or order at the counter.", until.Local().Format("3:04 pm"))
+	}
+
+	var activeItems int
+	db.QueryRow(`SELECT COUNT(*) FROM order_items oi JOIN orders o ON o.id = oi.order_id
+		WHERE o.status = 'Paid' AND COALESCE(oi.voided, 0) = 0 AND o.created_at >= datetime('now', '-24 hours')`).Scan(&activeItems)
+	wait := currentWaitMinutes()
+
+	if activeItems >= pauseActiveItems || wait >= pauseWaitMinutes {
+		return false, "Our kitchen is at full capacity right now. Online ordering will reopen shortly."
+	}
+
+	if wait >= throttleWaitMinutes {
+		var recent int
+		db.QueryRow(`SELECT COUNT(*) FROM orders WHERE channel = 'web' AND created_at >= datetime('now', '-10 minutes')`).Scan(&recent)
+		if recent >= throttleOrdersPer10m {
+			return false, fmt.Sprintf("We're very busy (about %d mins wait). Please try again in a few minutes.", wait)
+		}
+	}
+	return true, ""
+}
+
+// pausedUntil returns the end of a manual pause, or the zero time
+func pausedUntil() time.Time {
+	t, _ := time.Parse(time.RFC3339, getSetting(pausedUntilKey, ""))
+	return t
+}
+
+// handleOrderingBanner shows customers why they can't check out (empty when open)
+func handleOrderingBanner(w http.ResponseWriter, r *http.Request) {
+	if open, msg := orderingStatus(requestChannel(r)); !open {
+		fmt.Fprintf(w, `
+			<div class="bg-red-50 border border-red-200 text-red-700 rounded-xl px-4 py-3 text-sm font-medium flex items-center gap-2">
+				<span class="text-xl">⏸️</span><span>%s</span>
+			</div>`, msg)
+	}
+}
+
+// handleKitchenPause sets (minutes > 0) or clears (minutes = 0) a manual pause from the KDS
+func handleKitchenPause(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		minutes, _ := strconv.Atoi(r.URL.Query().Get("minutes"))
+		until := ""
+		if minutes > 0 {
+			until = time.Now().Add(time.Duration(minutes) * time.Minute).UTC().Format(time.RFC3339)
+		}
+		if err := setSetting(pausedUntilKey, until); err != nil {
+			fmt.Printf("Error saving pause: %v", err)
+		}
+	}
+	renderPauseControl(w)
+}
+
+// renderPauseControl draws the online ordering switch in the KDS header
+func renderPauseControl(w http.ResponseWriter) {
+	open, _ := orderingStatus("web")
+	btn := `class="icon-btn" style="font-size:0.9rem; width:auto; padding:0 10px;" hx-target="#pause-control" hx-swap="innerHTML"`
+
+	if open {
+		fmt.Fprintf(w, `
+			<span style="color:#2ecc71; font-weight:bold;">● ONLINE OPEN</span>
+			<div %s hx-post="/kitchen/pause?minutes=15">⏸ 15m</div>
+			<div %s hx-post="/kitchen/pause?minutes=30">⏸ 30m</div>
+			<div %s hx-post="/kitchen/pause?minutes=60">⏸ 60m</div>`, btn, btn, btn)
+		return
+	}
+
+	// Auto-pauses lift by themselves once the load drops, so only manual pauses get a Resume button
+	if until := pausedUntil(); time.Now().Before(until) {
+		fmt.Fprintf(w, `
+			<span style="color:#e74c3c; font-weight:bold;">● PAUSED until %s</span>
+			<div %s hx-post="/kitchen/pause?minutes=0">▶ Resume</div>`, until.Local().Format("3:04 pm"), btn)
+		return
+	}
+	fmt.Fprint(w, `<span style="color:#e74c3c; font-weight:bold;">● AUTO-PAUSED (kitchen busy)</span>`)
+}
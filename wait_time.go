@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Wait-time estimation settings
+const (
+	kitchenCapacity    = 3   // Items the kitchen works on at the same time
+	minPrepSamples     = 3   // Samples needed before trusting a per-product average
+	prepSampleWindow   = 500 // Only the most recent prep times are averaged
+	defaultPrepMinutes = 10.0
+)
+
+// Fallback prep times (minutes) per category until enough history is recorded
+var defaultCategoryPrep = map[string]float64{
+	"pizza": 15,
+	"pasta": 12,
+	"drink": 4,
+}
+
+// prepStats holds average prep seconds learned from completed orders
+type prepStats struct {
+	byProduct  map[string]float64
+	byCategory map[string]float64
+}
+
+// productCategoryJoin finds each order line's product by id; lines saved before product ids
+// were stored fall back to the name
+const productCategoryJoin = `LEFT JOIN products p ON p.id = oi.product_id OR (COALESCE(oi.product_id, 0) = 0 AND p.name = oi.product_name)`
+
+// recordPrepTimes stores how long the kitchen spent on an order. Prep starts when the order
+// was placed or, if the kitchen was still busy then, when it finished the previous order,
+// so time spent waiting in the queue isn't counted. The sample goes to the line that sets
+// the pace (the slowest one by current estimates), not to every line of the order.
+func recordPrepTimes(orderID string) {
+	// A restored and re-completed order replaces its earlier samples
+	db.Exec("DELETE FROM prep_times WHERE order_id = ?", orderID)
+
+	var seconds int
+	err := db.QueryRow(`
+		SELECT CAST((julianday('now') - julianday(MAX(o.created_at, COALESCE((
+			SELECT MAX(e.created_at) FROM order_events e
+			WHERE e.event = 'status' AND e.detail = 'Completed' AND e.order_id != o.id), o.created_at)))) * 86400 AS INTEGER)
+		FROM orders o WHERE o.id = ?`, orderID).Scan(&seconds)
+	if err != nil {
+		fmt.Println("Error recording prep times:", err)
+		return
+	}
+
+	rows, err := db.Query(`SELECT oi.product_name, COALESCE(p.category, '') FROM order_items oi `+productCategoryJoin+`
+		WHERE oi.order_id = ? AND COALESCE(oi.voided, 0) = 0`, orderID)
+	if err != nil {
+		fmt.Println("Error recording prep times:", err)
+		return
+	}
+	stats := loadPrepStats()
+	var name, category string
+	slowest := -1.0
+	for rows.Next() {
+		var n, c string
+		rows.Scan(&n, &c)
+		if m := stats.itemMinutes(n, c); m > slowest {
+			slowest, name, category = m, n, c
+		}
+	}
+	rows.Close()
+	if slowest < 0 {
+		return
+	}
+
+	if _, err := db.Exec("INSERT INTO prep_times (order_id, product_name, category, seconds) VALUES (?, ?, ?, ?)",
+		orderID, name, category, seconds); err != nil {
+		fmt.Println("Error recording prep times:", err)
+	}
+}
+
+func loadPrepStats() prepStats {
+	stats := prepStats{byProduct: map[string]float64{}, byCategory: map[string]float64{}}
+
+	rows, err := db.Query(`
+		SELECT product_name, category, AVG(seconds), COUNT(*)
+		FROM (SELECT * FROM prep_times ORDER BY id DESC LIMIT ?)
+		GROUP BY product_name, category`, prepSampleWindow)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return stats
+	}
+	defer rows.Close()
+
+	catTotals := map[string]float64{}
+	catCounts := map[string]int{}
+	for rows.Next() {
+		var name, cat string
+		var avg float64
+		var count int
+		rows.Scan(&name, &cat, &avg, &count)
+		if count >= minPrepSamples {
+			stats.byProduct[name] = avg
+		}
+		catTotals[cat] += avg * float64(count)
+		catCounts[cat] += count
+	}
+	for cat, total := range catTotals {
+		if catCounts[cat] >= minPrepSamples {
+			stats.byCategory[cat] = total / float64(catCounts[cat])
+		}
+	}
+	return stats
+}
+
+// itemMinutes is the expected prep time of one item
+func (s prepStats) itemMinutes(name, category string) float64 {
+	if sec, ok := s.byProduct[name]; ok {
+		return sec / 60
+	}
+	if sec, ok := s.byCategory[category]; ok {
+		return sec / 60
+	}
+	if m, ok := defaultCategoryPrep[category]; ok {
+		return m
+	}
+	return defaultPrepMinutes
+}
+
+// queueMinutes is the backlog of work currently on the kitchen board
+func (s prepStats) queueMinutes() float64 {
+	rows, err := db.Query(`
+		SELECT oi.product_name, COALESCE(p.category, '')
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		` + productCategoryJoin + `
+		WHERE o.status = 'Paid' AND COALESCE(oi.voided, 0) = 0 AND o.created_at >= datetime('now', '-24 hours')`)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return 0
+	}
+	defer rows.Close()
+
+	total := 0.0
+	for rows.Next() {
+		var name, cat string
+		rows.Scan(&name, &cat)
+		total += s.itemMinutes(name, cat)
+	}
+	return total / kitchenCapacity
+}
+
+// estimateWaitMinutes predicts how long a new order with the given items would
+// take: the current queue plus the slowest item in the order.
+func estimateWaitMinutes(items []CartItem) int {
+	stats := loadPrepStats()
+
+	slowest := 0.0
+	for _, item := range items {
+		var category string
+		db.QueryRow("SELECT category FROM products WHERE id = ?", item.ProductID).Scan(&category)
+		slowest = math.Max(slowest, stats.itemMinutes(item.Name, category))
+	}
+	if len(items) == 0 {
+		slowest = defaultPrepMinutes
+	}
+
+	return int(math.Ceil(stats.queueMinutes() + slowest))
+}
+
+// currentWaitMinutes is the estimate for a typical order right now (used by the landing page)
+func currentWaitMinutes() int {
+	return estimateWaitMinutes(nil)
+}
+
+// formatReadyTime turns an estimate into a clock time for customers
+func formatReadyTime(minutes int) string {
+	return time.Now().Add(time.Duration(minutes) * time.Minute).Format("3:04 pm")
+}
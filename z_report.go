@@ -0,0 +1,376 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Cash totals are rounded to the nearest 5 sen at the counter
+const cashRoundingStep = 0.05
+
+// ZReport is the end-of-day summary, stored as JSON when the day is closed so the
+// archive never changes afterwards.
+type ZReport struct {
+	BusinessDay  string
+	Orders       int
+	Cancelled    int
+	ByChannel    map[string]int
+	GrossSales   float64 // Everything charged, incl. tax
+	Discounts    float64 // No discounts are offered yet; kept so the layout doesn't change later
+	Tax          float64
+	Refunds      float64
+	NetSales     float64
+	Payments     map[string]float64 // By payment method, before refunds
+	CashRefunds  float64
+	Rounding     float64 // Cash rounding difference (positive = collected more)
+	OpeningFloat float64
+	ExpectedCash float64
+	CountedCash  float64
+	Variance     float64
+	ClosedAt     string
+}
+
+// roundCash rounds an amount to what can be paid in coins
+func roundCash(amount float64) float64 {
+	return math.Round(amount/cashRoundingStep) * cashRoundingStep
+}
+
+// dayClosed reports whether a business day has been closed (and is locked against edits)
+func dayClosed(day string) bool {
+	return dayClosedIn(db, day)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// dayClosedIn is dayClosed read through a transaction
+func dayClosedIn(q queryer, day string) bool {
+	var closedAt sql.NullString
+	q.QueryRow("SELECT closed_at FROM business_days WHERE business_day = ?", day).Scan(&closedAt)
+	return closedAt.Valid
+}
+
+// closableDay reports whether day is a valid business day that has already started
+func closableDay(day string) bool {
+	_, err := time.Parse("2006-01-02", day)
+	return err == nil && day <= businessDay(time.Now())
+}
+
+// orderLocked reports whether an order belongs to a closed day
+func orderLocked(orderID interface{}) bool {
+	var day sql.NullString
+	db.QueryRow("SELECT business_day FROM orders WHERE id = ?", orderID).Scan(&day)
+	return day.Valid && dayClosed(day.String)
+}
+
+// buildZReport computes the figures for a business day from orders and refunds
+func buildZReport(q queryer, day string) ZReport {
+	z := ZReport{BusinessDay: day, ByChannel: map[string]int{}, Payments: map[string]float64{}}
+
+	rows, err := q.Query(`SELECT status, total_amount, COALESCE(channel, 'web'), COALESCE(payment_method, 'card')
+		FROM orders WHERE business_day = ?`, day)
+	if err != nil {
+		fmt.Println("DB Error:", err)
+		return z
+	}
+	for rows.Next() {
+		var status, channel, method string
+		var total float64
+		rows.Scan(&status, &total, &channel, &method)
+		z.Orders++
+		z.ByChannel[channel]++
+		// Cancelled orders were refunded in full, so they take no part in sales or the drawer
+		if status == StatusCancelled {
+			z.Cancelled++
+			continue
+		}
+		z.GrossSales += total
+		z.Payments[method] += total
+		if method == "cash" {
+			z.Rounding += roundCash(total) - total
+		}
+	}
+	rows.Close()
+
+	// Refunds are counted on the day they were issued, whichever day the order was placed
+	// (except on cancelled orders, which are left out of sales altogether)
+	q.QueryRow(`SELECT COALESCE(SUM(rf.amount), 0), COALESCE(SUM(CASE WHEN o.payment_method = 'cash' THEN rf.amount ELSE 0 END), 0)
+		FROM refunds rf JOIN orders o ON o.id = rf.order_id
+		WHERE rf.status != 'failed' AND o.status != ? AND date(rf.created_at, 'localtime', ?) = ?`,
+		StatusCancelled, fmt.Sprintf("-%d hours", businessDayStartHour()), day).Scan(&z.Refunds, &z.CashRefunds)
+
+	z.Tax = z.GrossSales / (1 + taxRate) * taxRate
+	z.NetSales = z.GrossSales - z.Discounts - z.Refunds
+
+	q.QueryRow("SELECT COALESCE(opening_float, 0) FROM business_days WHERE business_day = ?", day).Scan(&z.OpeningFloat)
+	z.ExpectedCash = z.OpeningFloat + z.Payments["cash"] + z.Rounding - z.CashRefunds
+	return z
+}
+
+// loadClosedZReport returns the archived report of a closed day
+func loadClosedZReport(day string) (ZReport, bool) {
+	var data string
+	if err := db.QueryRow("SELECT z_report FROM business_days WHERE business_day = ? AND closed_at IS NOT NULL", day).Scan(&data); err != nil {
+		return ZReport{}, false
+	}
+	var z ZReport
+	if err := json.Unmarshal([]byte(data), &z); err != nil {
+		return ZReport{}, false
+	}
+	return z, true
+}
+
+// writeZReportLines prints the report body (shared by the close page and the printout)
+func writeZReportLines(w http.ResponseWriter, z ZReport) {
+	line := func(label string, amount float64) {
+		if amount = math.Round(amount*100) / 100; amount == 0 {
+			amount = 0 // Avoid printing "-0.00"
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td style=\"text-align:right\">RM%.2f</td></tr>", label, amount)
+	}
+	fmt.Fprintf(w, `<table style="width:100%%">
+		<tr><td>Orders</td><td style="text-align:right">%d</td></tr>
+		<tr><td>Cancelled</td><td style="text-align:right">%d</td></tr>`, z.Orders, z.Cancelled)
+	for _, ch := range sortedKeys(z.ByChannel) {
+		fmt.Fprintf(w, "<tr><td>&nbsp;&nbsp;%s</td><td style=\"text-align:right\">%d</td></tr>", ch, z.ByChannel[ch])
+	}
+	fmt.Fprint(w, `<tr><td colspan="2"><hr></td></tr>`)
+	line("Gross sales", z.GrossSales)
+	line("Discounts", -z.Discounts)
+	line("Refunds", -z.Refunds)
+	line("Net sales", z.NetSales)
+	line("Tax included", z.Tax)
+	fmt.Fprint(w, `<tr><td colspan="2"><hr><b>Payments</b></td></tr>`)
+	for _, method := range paymentMethods {
+		line("&nbsp;&nbsp;"+method, z.Payments[method])
+	}
+	line("Cash rounding", z.Rounding)
+	fmt.Fprint(w, `<tr><td colspan="2"><hr><b>Cash drawer</b></td></tr>`)
+	line("Opening float", z.OpeningFloat)
+	line("Cash refunds", -z.CashRefunds)
+	line("Expected cash", z.ExpectedCash)
+	if z.ClosedAt != "" {
+		line("Counted cash", z.CountedCash)
+		line("Variance", z.Variance)
+	}
+	fmt.Fprint(w, `</table>`)
+}
+
+func sortedKeys(m map[string]int) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// handleAdminClose shows today's (or ?day=) figures with the float and close forms
+func handleAdminClose(w http.ResponseWriter, r *http.Request) {
+	day := r.URL.Query().Get("day")
+	if day == "" {
+		day = businessDay(time.Now())
+	}
+
+	adminPageStart(w, "Close Day")
+	z, closed := loadClosedZReport(day)
+	if !closed {
+		z = buildZReport(db, day)
+	}
+
+	badge := `<span class="text-sm bg-green-100 text-green-800 rounded px-2 py-1">OPEN</span>`
+	if closed {
+		badge = `<span class="text-sm bg-gray-900 text-white rounded px-2 py-1">🔒 CLOSED</span>`
+	}
+	fmt.Fprintf(w, `
+    <div class="grid md:grid-cols-3 gap-6">
+        <section class="md:col-span-2 bg-white rounded-lg shadow p-6 font-mono text-sm">
+            <h1 class="text-2xl font-black font-sans mb-4">Z report · %s %s</h1>`, html.EscapeString(day), badge)
+	writeZReportLines(w, z)
+	fmt.Fprint(w, `</section><aside class="space-y-4">`)
+
+	if closed {
+		fmt.Fprintf(w, `
+            <div class="bg-white rounded-lg shadow p-4 text-sm">
+                <p>Closed %s. Orders of this day can no longer be changed or refunded.</p>
+                <a href="/admin/close/print?day=%s" target="_blank" class="block mt-3 text-center bg-gray-900 text-white rounded py-1.5 font-semibold">🖨 Print Z report</a>
+            </div>`, parseDBTime(z.ClosedAt).Local().Format("Jan 2, 3:04 pm"), html.EscapeString(day))
+	} else {
+		fmt.Fprintf(w, `
+            <form method="post" action="/admin/close/float" class="bg-white rounded-lg shadow p-4 space-y-2 text-sm">
+                <h3 class="font-bold">💵 Opening float</h3>
+                <input type="hidden" name="day" value="%s">
+                <input type="number" name="amount" step="0.05" min="0" value="%.2f" class="w-full border rounded px-2 py-1">
+                <button class="w-full bg-gray-900 text-white rounded py-1.5 font-semibold">Save float</button>
+            </form>
+            <form method="post" action="/admin/close/submit" class="bg-white rounded-lg shadow p-4 space-y-2 text-sm">
+                <h3 class="font-bold">🔒 Count drawer &amp; close day</h3>
+                <input type="hidden" name="day" value="%s">
+                <label class="block">Counted cash <input type="number" name="counted" step="0.05" min="0" required class="w-full border rounded px-2 py-1"></label>
+                <p class="text-xs text-gray-500">Closing locks this day's orders against edits and refunds.</p>
+                <button class="w-full bg-red-600 text-white rounded py-1.5 font-semibold" onclick="return confirm('Close this business day? This cannot be undone.')">Close day</button>
+            </form>`, html.EscapeString(day), z.OpeningFloat, html.EscapeString(day))
+	}
+
+	fmt.Fprintf(w, `
+            <form method="post" action="/admin/close/day-start" class="bg-white rounded-lg shadow p-4 space-y-2 text-sm">
+                <h3 class="font-bold">🕓 Business day starts at</h3>
+                <input type="number" name="hour" min="0" max="23" value="%d" class="w-20 border rounded px-2 py-1">:00
+                <p class="text-xs text-gray-500">Orders before this hour count towards the previous day. Applies to orders from now on.</p>
+                <button class="w-full bg-gray-900 text-white rounded py-1.5 font-semibold">Save</button>
+            </form>`, businessDayStartHour())
+
+	// Archive of past closes
+	fmt.Fprint(w, `<div class="bg-white rounded-lg shadow p-4 text-sm"><h3 class="font-bold mb-2">📚 Past closes</h3><ul>`)
+	rows, err := db.Query("SELECT business_day, COALESCE(counted_cash, 0) - COALESCE(expected_cash, 0) FROM business_days WHERE closed_at IS NOT NULL ORDER BY business_day DESC LIMIT 60")
+	if err == nil {
+		for rows.Next() {
+			var d string
+			var variance float64
+			rows.Scan(&d, &variance)
+			fmt.Fprintf(w, `<li class="flex justify-between border-t py-1"><a href="/admin/close?day=%s" class="text-blue-600 hover:underline">%s</a><span>%+.2f</span></li>`, d, d, variance)
+		}
+		rows.Close()
+	}
+	fmt.Fprint(w, `</ul></div></aside></div>`)
+	adminPageEnd(w)
+}
+
+// handleAdminCloseFloat records the cash put in the drawer at opening
+func handleAdminCloseFloat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	day := r.FormValue("day")
+	if !closableDay(day) {
+		http.Error(w, "Only today or an earlier business day can be closed", http.StatusBadRequest)
+		return
+	}
+	if dayClosed(day) {
+		http.Error(w, "This business day is already closed", http.StatusConflict)
+		return
+	}
+	amount, _ := strconv.ParseFloat(r.FormValue("amount"), 64)
+	_, err := db.Exec(`INSERT INTO business_days (business_day, opening_float) VALUES (?, ?)
+		ON CONFLICT(business_day) DO UPDATE SET opening_float = excluded.opening_float`, day, amount)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/close?day="+day, http.StatusSeeOther)
+}
+
+// handleAdminDayStart sets the hour the business day (and the pickup number sequence) rolls over
+func handleAdminDayStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hour, err := strconv.Atoi(r.FormValue("hour"))
+	if err != nil || hour < 0 || hour > 23 {
+		http.Error(w, "Hour must be between 0 and 23", http.StatusBadRequest)
+		return
+	}
+	if err := setSetting(dayStartHourKey, strconv.Itoa(hour)); err != nil {
+		fmt.Println("DB Error:", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/close", http.StatusSeeOther)
+}
+
+// handleAdminCloseSubmit records the counted cash, archives the Z report and locks the day
+func handleAdminCloseSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	day := r.FormValue("day")
+	if !closableDay(day) {
+		http.Error(w, "Only today or an earlier business day can be closed", http.StatusBadRequest)
+		return
+	}
+	counted, err := strconv.ParseFloat(r.FormValue("counted"), 64)
+	if err != nil {
+		http.Error(w, "Enter the counted cash", http.StatusBadRequest)
+		return
+	}
+
+	// One immediate transaction: the day is marked closed before the report is built, so no
+	// order or refund can land in between and be locked in without being counted
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if dayClosedIn(tx, day) {
+		http.Error(w, "This business day is already closed", http.StatusConflict)
+		return
+	}
+	closedAt := time.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.Exec(`INSERT INTO business_days (business_day, closed_at) VALUES (?, ?)
+		ON CONFLICT(business_day) DO UPDATE SET closed_at = excluded.closed_at`, day, closedAt); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	z := buildZReport(tx, day)
+	z.CountedCash = counted
+	z.Variance = counted - z.ExpectedCash
+	z.ClosedAt = closedAt
+	data, _ := json.Marshal(z)
+
+	if _, err := tx.Exec("UPDATE business_days SET counted_cash = ?, expected_cash = ?, z_report = ? WHERE business_day = ?",
+		counted, z.ExpectedCash, string(data), day); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if z.Variance != 0 {
+		notify("Cash variance", fmt.Sprintf("%s closed with a drawer variance of RM%+.2f", day, z.Variance))
+	}
+	http.Redirect(w, r, "/admin/close?day="+day, http.StatusSeeOther)
+}
+
+// handleAdminClosePrint renders an archived Z report for the receipt printer
+func handleAdminClosePrint(w http.ResponseWriter, r *http.Request) {
+	z, ok := loadClosedZReport(r.URL.Query().Get("day"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Z report %s</title>
+    <style>
+        body { font-family: monospace; width: 72mm; margin: 0 auto; padding: 4mm 0; font-size: 10pt; }
+        h1 { text-align: center; font-size: 16pt; margin: 0; }
+        .meta { text-align: center; border-bottom: 1px dashed #000; padding-bottom: 6px; margin-bottom: 6px; }
+        hr { border: none; border-top: 1px dashed #000; }
+    </style>
+</head>
+<body onload="window.print()">
+    <h1>Z REPORT</h1>
+    <div class="meta">%s<br>Closed %s</div>`, z.BusinessDay, z.BusinessDay, parseDBTime(z.ClosedAt).Local().Format("Jan 2 2006, 3:04 pm"))
+	writeZReportLines(w, z)
+	fmt.Fprint(w, `
+</body>
+</html>`)
+}